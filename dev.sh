@@ -6,6 +6,7 @@ Usage: $(basename $0) <command>
 
 Wrappers around core binaries:
     run                    Runs k8s-status locally.
+    test                   Runs unit tests and fixture tests against the fake cluster.
     build                  Builds backend - static binary is in 'bin' directory.
     docker-build           Builds docker image based on existing go binary.
     docker-push            Pushes docker image to dockerhub.
@@ -44,6 +45,10 @@ run() {
 	K8STATUS_DEBUG=true K8STATUS_KUBECONFIGPATH=~/.kube/config go run main.go 
 }
 
+runTests() {
+	go test ./pkg/...
+}
+
 CMD="$1"
 SUBCMD="$2"
 shift
@@ -51,6 +56,9 @@ case "$CMD" in
 	run)
 		run
 	;;
+	test)
+		runTests
+	;;
 	build)
 		build
 	;;
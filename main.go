@@ -1,6 +1,8 @@
 package main
 
 import (
+	"time"
+
 	"github.com/mateuszdyminski/k8s-status/pkg/config"
 	"github.com/mateuszdyminski/k8s-status/pkg/runner"
 	"github.com/mateuszdyminski/k8s-status/pkg/server"
@@ -15,10 +17,11 @@ func main() {
 		log.Fatal().Msgf("can't load config file. err: %s", err)
 	}
 
-	runner, err := runner.NewRunnerWithCfg(cfg)
+	healthRunner, err := runner.NewRunnerWithCfg(cfg)
 	if err != nil {
 		log.Fatal().Msgf("can't create health runner. err: %s", err)
 	}
+	healthRunner.SetMetrics(runner.NewMetrics())
 
 	zerolog.SetGlobalLevel(zerolog.InfoLevel)
 	if cfg.Debug {
@@ -26,5 +29,9 @@ func main() {
 	}
 
 	ctx := signals.SetupSignalContext()
-	server.ListenAndServe(ctx, runner, cfg)
+
+	scheduler := runner.NewScheduler(healthRunner, time.Duration(cfg.ProbeInterval)*time.Second)
+	go scheduler.Start(ctx)
+
+	server.ListenAndServe(ctx, scheduler, cfg)
 }
@@ -0,0 +1,137 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"time"
+
+	"github.com/ghodss/yaml"
+)
+
+// CheckerSpec describes a single checker instance.
+type CheckerSpec struct {
+	// Type is the name of the registered checker type
+	Type string `json:"type"`
+	// Name is the unique name of the checker instance
+	Name string `json:"name"`
+	// Enabled allows to turn off the checker without removing it, checkers are enabled by default
+	Enabled *bool `json:"enabled,omitempty"`
+	// Severity overrides the severity of probes failed by the checker
+	Severity string `json:"severity,omitempty"`
+	// Timeout overrides the default checker timeout
+	Timeout Duration `json:"timeout"`
+	// DependsOn are names of checkers which must succeed before this checker runs
+	DependsOn []string `json:"dependsOn,omitempty"`
+	// Tags group checkers, i.e. network, so /healthz can be filtered by them
+	Tags []string `json:"tags,omitempty"`
+	// Params are the parameters specific to the checker type
+	Params map[string]interface{} `json:"params,omitempty"`
+}
+
+// IsEnabled returns true when checker should be run.
+func (s CheckerSpec) IsEnabled() bool {
+	return s.Enabled == nil || *s.Enabled
+}
+
+// checkersFile is the content of the checkers config file.
+type checkersFile struct {
+	Checkers []CheckerSpec `json:"checkers"`
+}
+
+// LoadCheckers loads checker specs from YAML or JSON file.
+func LoadCheckers(path string) ([]CheckerSpec, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file checkersFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+
+	if err := ValidateCheckers(file.Checkers); err != nil {
+		return nil, err
+	}
+	return file.Checkers, nil
+}
+
+// ValidateCheckers checks that all checkers have a type and a unique name.
+func ValidateCheckers(specs []CheckerSpec) error {
+	names := make(map[string]bool)
+	for i, spec := range specs {
+		if spec.Type == "" {
+			return fmt.Errorf("checker #%d has no type", i)
+		}
+		if spec.Name == "" {
+			return fmt.Errorf("checker #%d of type %s has no name", i, spec.Type)
+		}
+		if names[spec.Name] {
+			return fmt.Errorf("checker name %s is not unique", spec.Name)
+		}
+		names[spec.Name] = true
+	}
+	return nil
+}
+
+// ConfigCheckerName is the name of the default checker of the cluster config
+const ConfigCheckerName = "cluster-config"
+
+// DefaultCheckers returns checker specs built from env vars, used when no checkers file is set.
+// The cluster config is checked only when the name of its config map is set.
+func DefaultCheckers(c *Config) []CheckerSpec {
+	apiServer := []string{"apiserver"}
+	specs := []CheckerSpec{{Type: "apiserver", Name: "apiserver"}}
+	if c.ConfigCheckerConfigName != "" {
+		specs = append(specs, CheckerSpec{
+			Type:      "clusterconfig",
+			Name:      ConfigCheckerName,
+			DependsOn: apiServer,
+			Params: map[string]interface{}{
+				"namespace": c.ConfigCheckerNamespace,
+				"name":      c.ConfigCheckerConfigName,
+			},
+		})
+	}
+	return append(specs,
+		CheckerSpec{Type: "componentstatus", Name: "etcd", DependsOn: apiServer, Params: map[string]interface{}{"component": "etcd"}},
+		CheckerSpec{Type: "componentstatus", Name: "scheduler", DependsOn: apiServer, Params: map[string]interface{}{"component": "scheduler"}},
+		CheckerSpec{Type: "componentstatus", Name: "controller-manager", DependsOn: apiServer, Params: map[string]interface{}{"component": "controller-manager"}},
+		CheckerSpec{Type: "nodes", Name: "nodesstatus", DependsOn: apiServer, Params: map[string]interface{}{"readyThreshold": c.KubeNodesReadyThreshold}},
+	)
+}
+
+// Duration is time.Duration which can be read from a string like "5s" or a number of seconds.
+type Duration struct {
+	time.Duration
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+
+	switch v := value.(type) {
+	case float64:
+		d.Duration = time.Duration(v * float64(time.Second))
+	case string:
+		duration, err := time.ParseDuration(v)
+		if err != nil {
+			return err
+		}
+		d.Duration = duration
+	default:
+		return fmt.Errorf("invalid duration: %s", data)
+	}
+
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(d.String())), nil
+}
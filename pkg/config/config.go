@@ -1,6 +1,10 @@
 package config
 
-import "github.com/kelseyhightower/envconfig"
+import (
+	"fmt"
+
+	"github.com/kelseyhightower/envconfig"
+)
 
 // Config holds configuration.
 type Config struct {
@@ -9,15 +13,39 @@ type Config struct {
 	GracefulShutdownExtraSleep int
 	Debug                      bool
 
+	// Status codes of /healthz by the cluster status, degraded is reported as failed in strict mode
+	HealthyStatusCode  int
+	DegradedStatusCode int
+	FailedStatusCode   int
+
+	// LivenessTimeout is the number of seconds without progress of the probe scheduler
+	// after which /livez fails, 3 probe intervals are used when it's not set
+	LivenessTimeout int
+
+	// Runner config
+	CheckerConcurrency int
+	CheckerTimeout     int
+	ProbeInterval      int
+
+	// Kubernetes access config, in-cluster config is used when none of them is set
+	KubeConfigPath  string
+	KubeContext     string
+	KubeImpersonate string
+
 	// Kubernetes nodes config
 	KubeNodesReadyThreshold int
 
 	// Config checker
 	ConfigCheckerNamespace  string
 	ConfigCheckerConfigName string
+
+	// CheckersConfigPath is the path to YAML or JSON file with checkers,
+	// checkers are built from env vars when it's not set
+	CheckersConfigPath string
+	Checkers           []CheckerSpec `ignored:"true"`
 }
 
-// LoadConfig loads config from env vars.
+// LoadConfig loads config from env vars and checkers from the checkers config file.
 func LoadConfig() (*Config, error) {
 	var c Config
 	err := envconfig.Process("k8status", &c)
@@ -25,5 +53,17 @@ func LoadConfig() (*Config, error) {
 		return nil, err
 	}
 
+	if c.CheckersConfigPath != "" {
+		c.Checkers, err = LoadCheckers(c.CheckersConfigPath)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		c.Checkers = DefaultCheckers(&c)
+		if err := ValidateCheckers(c.Checkers); err != nil {
+			return nil, fmt.Errorf("invalid checkers built from env vars: %s", err)
+		}
+	}
+
 	return &c, nil
 }
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"os"
+	"testing"
+)
+
+func TestLoadConfigDefaultCheckers(t *testing.T) {
+	os.Unsetenv("K8STATUS_CHECKERSCONFIGPATH")
+	os.Unsetenv("K8STATUS_CONFIGCHECKERCONFIGNAME")
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("expected default checkers without the config checker, got %s", err)
+	}
+	for _, spec := range cfg.Checkers {
+		if spec.Type == "clusterconfig" {
+			t.Errorf("expected no config checker when its config name is not set, got %+v", spec)
+		}
+	}
+
+	// the config name doesn't clash with names of other checkers
+	os.Setenv("K8STATUS_CONFIGCHECKERCONFIGNAME", "etcd")
+	defer os.Unsetenv("K8STATUS_CONFIGCHECKERCONFIGNAME")
+	cfg, err = LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Checkers) < 2 || cfg.Checkers[1].Name != ConfigCheckerName || cfg.Checkers[1].Params["name"] != "etcd" {
+		t.Errorf("expected default checkers with the config checker, got %+v", cfg.Checkers)
+	}
+}
+
+func TestValidateCheckers(t *testing.T) {
+	tests := []struct {
+		specs []CheckerSpec
+		err   string
+	}{
+		{specs: []CheckerSpec{{Type: "apiserver", Name: "apiserver"}, {Type: "nodes", Name: "nodes"}}},
+		{specs: []CheckerSpec{{Name: "apiserver"}}, err: "checker #0 has no type"},
+		{specs: []CheckerSpec{{Type: "apiserver", Name: "api"}, {Type: "http", Name: "api"}}, err: "checker name api is not unique"},
+	}
+
+	for _, test := range tests {
+		err := ValidateCheckers(test.specs)
+		if (err == nil && test.err != "") || (err != nil && err.Error() != test.err) {
+			t.Errorf("%+v: expected error %q, got %v", test.specs, test.err, err)
+		}
+	}
+}
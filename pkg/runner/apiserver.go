@@ -0,0 +1,144 @@
+package runner
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"k8s.io/client-go/rest"
+)
+
+// APIServerCheckerID identifies the checker that validates health endpoints of the API server
+const APIServerCheckerID = "apiserver"
+
+// defaultAPIServerEndpoints are the health endpoints of the API server checked by default
+var defaultAPIServerEndpoints = []string{"/livez", "/readyz", "/healthz"}
+
+// verboseCheckLine matches a single check in the verbose output, i.e. "[-]etcd failed: reason withheld"
+var verboseCheckLine = regexp.MustCompile(`^\[([+-])\](\S+) (.*)$`)
+
+// APIServerCheck is a single check reported by the API server health endpoint
+type APIServerCheck struct {
+	// Endpoint is the health endpoint which reported the check
+	Endpoint string `json:"endpoint"`
+	// Name is the name of the check, i.e. etcd or poststarthook/start-informers
+	Name string `json:"name"`
+	// Healthy is true when the check passed
+	Healthy bool `json:"healthy"`
+	// Message is the message reported for the check
+	Message string `json:"message,omitempty"`
+}
+
+// NewAPIServerChecker returns a Checker that validates health endpoints of the API server
+func NewAPIServerChecker(config KubeConfig, name string, endpoints []string) Checker {
+	if len(endpoints) == 0 {
+		endpoints = defaultAPIServerEndpoints
+	}
+
+	return &apiServerChecker{
+		name:      name,
+		client:    config.Client.Discovery().RESTClient(),
+		endpoints: endpoints,
+	}
+}
+
+// apiServerChecker calls raw health endpoints of the API server and reports every failed check
+type apiServerChecker struct {
+	name      string
+	client    rest.Interface
+	endpoints []string
+}
+
+// Name returns the name of this checker
+func (r *apiServerChecker) Name() string { return r.name }
+
+// Check validates the health endpoints of the API server
+func (r *apiServerChecker) Check(ctx context.Context, reporter Reporter) {
+	var checks []APIServerCheck
+	var failed []string
+	var supported int
+
+	for _, endpoint := range r.endpoints {
+		endpointChecks, supportedEndpoint, err := r.checkEndpoint(ctx, endpoint)
+		if supportedEndpoint {
+			supported++
+		}
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %s", endpoint, err))
+		}
+		for _, check := range endpointChecks {
+			if !check.Healthy {
+				failed = append(failed, fmt.Sprintf("%s/%s", endpoint, check.Name))
+			}
+		}
+		checks = append(checks, endpointChecks...)
+	}
+
+	if supported == 0 && len(failed) == 0 {
+		failed = append(failed, fmt.Sprintf("none of endpoints %s is available", strings.Join(r.endpoints, ", ")))
+	}
+
+	if len(failed) > 0 {
+		reporter.Add(&Probe{
+			Checker:     r.Name(),
+			Status:      ProbeFailed,
+			Error:       fmt.Sprintf("API server checks failed: %s", strings.Join(failed, ", ")),
+			CheckerData: checks,
+		})
+		return
+	}
+
+	reporter.Add(&Probe{
+		Checker:     r.Name(),
+		Status:      ProbeRunning,
+		CheckerData: checks,
+	})
+}
+
+// checkEndpoint calls the endpoint in verbose mode and returns its checks.
+// Endpoints missing on older API servers are reported as not supported.
+func (r *apiServerChecker) checkEndpoint(ctx context.Context, endpoint string) ([]APIServerCheck, bool, error) {
+	var code int
+	body, err := r.client.Get().AbsPath(endpoint).Param("verbose", "").Context(ctx).Do().StatusCode(&code).Raw()
+	if code == http.StatusNotFound {
+		return nil, false, nil
+	}
+
+	checks := parseVerboseHealthz(endpoint, body)
+	if len(checks) > 0 {
+		return checks, true, nil
+	}
+
+	if err != nil {
+		return nil, true, err
+	}
+
+	// not verbose response, expect plain "ok"
+	return nil, true, kubeHealthz(bytes.NewReader(body))
+}
+
+// parseVerboseHealthz parses checks from the verbose output of the health endpoint
+func parseVerboseHealthz(endpoint string, body []byte) []APIServerCheck {
+	var checks []APIServerCheck
+
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		match := verboseCheckLine.FindStringSubmatch(strings.TrimSpace(scanner.Text()))
+		if match == nil {
+			continue
+		}
+
+		checks = append(checks, APIServerCheck{
+			Endpoint: endpoint,
+			Name:     match[2],
+			Healthy:  match[1] == "+",
+			Message:  match[3],
+		})
+	}
+
+	return checks
+}
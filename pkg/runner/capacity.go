@@ -0,0 +1,259 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+// unlabeledPool is the pool of nodes without the pool label
+const unlabeledPool = "unlabeled"
+
+// capacityResources are resources whose headroom is checked
+var capacityResources = []v1.ResourceName{v1.ResourceCPU, v1.ResourceMemory, v1.ResourcePods}
+
+// CapacityParams configures the capacity checker
+type CapacityParams struct {
+	// PoolLabel is the node label grouping nodes into pools, i.e. cloud.google.com/gke-nodepool
+	PoolLabel string `json:"poolLabel"`
+	// Headroom are minimal headroom percentages of cpu, memory and pods, checked overall and per pool
+	Headroom map[v1.ResourceName]HeadroomRule `json:"headroom"`
+}
+
+// HeadroomRule defines minimal percentages of allocatable resource which must not be requested
+type HeadroomRule struct {
+	// Warning is the headroom percentage below which a warning is reported, 0 disables it
+	Warning float64 `json:"warning"`
+	// Critical is the headroom percentage below which a critical failure is reported, 0 disables it
+	Critical float64 `json:"critical"`
+}
+
+// defaultHeadroomRules warns below 20% and fails below 10% of any resource
+func defaultHeadroomRules() map[v1.ResourceName]HeadroomRule {
+	rules := make(map[v1.ResourceName]HeadroomRule)
+	for _, name := range capacityResources {
+		rules[name] = HeadroomRule{Warning: 20, Critical: 10}
+	}
+	return rules
+}
+
+// ResourceHeadroom is the headroom of a single resource
+type ResourceHeadroom struct {
+	Allocatable string `json:"allocatable"`
+	Requested   string `json:"requested"`
+	// Headroom is the percentage of allocatable resource which is not requested
+	Headroom float64 `json:"headroom"`
+}
+
+// PoolCapacity is the capacity of schedulable nodes of a pool
+type PoolCapacity struct {
+	Pool      string                               `json:"pool"`
+	Nodes     int                                  `json:"nodes"`
+	Resources map[v1.ResourceName]ResourceHeadroom `json:"resources"`
+}
+
+// CapacitySummary is the data reported by the capacity checker
+type CapacitySummary struct {
+	Overall PoolCapacity   `json:"overall"`
+	Pools   []PoolCapacity `json:"pools,omitempty"`
+}
+
+// NewCapacityChecker returns a Checker that compares node allocatable resources with requests of scheduled pods
+func NewCapacityChecker(config KubeConfig, name string, params CapacityParams) (Checker, error) {
+	if params.Headroom == nil {
+		params.Headroom = defaultHeadroomRules()
+	}
+	for resourceName, rule := range params.Headroom {
+		if resourceName != v1.ResourceCPU && resourceName != v1.ResourceMemory && resourceName != v1.ResourcePods {
+			return nil, fmt.Errorf("unsupported resource %s", resourceName)
+		}
+		if rule.Critical > rule.Warning && rule.Warning > 0 {
+			return nil, fmt.Errorf("critical headroom of %s is greater than warning headroom", resourceName)
+		}
+	}
+
+	return &capacityChecker{
+		name:   name,
+		client: config.Client.CoreV1(),
+		params: params,
+	}, nil
+}
+
+// capacityChecker reports whether pods can still be scheduled on ready and schedulable nodes
+type capacityChecker struct {
+	name   string
+	client corev1.CoreV1Interface
+	params CapacityParams
+}
+
+// Name returns the name of this checker
+func (r *capacityChecker) Name() string { return r.name }
+
+// Check computes headroom overall and per pool and validates it against the rules
+func (r *capacityChecker) Check(ctx context.Context, reporter Reporter) {
+	nodes, err := r.client.Nodes().List(metav1.ListOptions{})
+	if err != nil {
+		reporter.Add(NewProbeFromErr(r.Name(), "failed to query nodes", err))
+		return
+	}
+
+	pods, err := r.client.Pods(metav1.NamespaceAll).List(metav1.ListOptions{
+		FieldSelector: fmt.Sprintf("status.phase!=%s,status.phase!=%s", v1.PodSucceeded, v1.PodFailed),
+	})
+	if err != nil {
+		reporter.Add(NewProbeFromErr(r.Name(), "failed to query pods", err))
+		return
+	}
+
+	summary := r.summarize(nodes.Items, pods.Items)
+
+	severity := ProbeNone
+	var problems []string
+	for _, pool := range append([]PoolCapacity{summary.Overall}, summary.Pools...) {
+		for _, resourceName := range capacityResources {
+			headroom, ok := pool.Resources[resourceName]
+			if !ok {
+				continue
+			}
+			rule := r.params.Headroom[resourceName]
+			switch {
+			case rule.Critical > 0 && headroom.Headroom < rule.Critical:
+				severity = ProbeCritical
+			case rule.Warning > 0 && headroom.Headroom < rule.Warning:
+				if severity != ProbeCritical {
+					severity = ProbeWarning
+				}
+			default:
+				continue
+			}
+			problems = append(problems, fmt.Sprintf("%s %s headroom %.1f%%", pool.Pool, resourceName, headroom.Headroom))
+		}
+	}
+
+	if len(problems) == 0 {
+		reporter.Add(&Probe{
+			Checker:     r.Name(),
+			Status:      ProbeRunning,
+			CheckerData: summary,
+		})
+		return
+	}
+
+	reporter.Add(&Probe{
+		Checker:     r.Name(),
+		Status:      ProbeFailed,
+		Severity:    severity,
+		Error:       fmt.Sprintf("low capacity headroom: %s", strings.Join(problems, ", ")),
+		CheckerData: summary,
+	})
+}
+
+// summarize sums allocatable resources of ready and schedulable nodes and requests of pods scheduled on them
+func (r *capacityChecker) summarize(nodes []v1.Node, pods []v1.Pod) CapacitySummary {
+	type totals struct {
+		nodes       int
+		allocatable v1.ResourceList
+		requested   v1.ResourceList
+	}
+
+	overall := &totals{allocatable: v1.ResourceList{}, requested: v1.ResourceList{}}
+	pools := make(map[string]*totals)
+	nodePools := make(map[string]string)
+
+	for _, node := range nodes {
+		if summary := summarizeNode(node, NodesRules{}); !summary.Ready || summary.Unschedulable {
+			continue
+		}
+
+		pool := ""
+		if r.params.PoolLabel != "" {
+			if pool = node.Labels[r.params.PoolLabel]; pool == "" {
+				pool = unlabeledPool
+			}
+			if pools[pool] == nil {
+				pools[pool] = &totals{allocatable: v1.ResourceList{}, requested: v1.ResourceList{}}
+			}
+			pools[pool].nodes++
+			addResources(pools[pool].allocatable, node.Status.Allocatable)
+		}
+		nodePools[node.Name] = pool
+		overall.nodes++
+		addResources(overall.allocatable, node.Status.Allocatable)
+	}
+
+	for _, pod := range pods {
+		pool, ok := nodePools[pod.Spec.NodeName]
+		if !ok {
+			continue
+		}
+		requests := podRequests(pod)
+		addResources(overall.requested, requests)
+		if pool != "" {
+			addResources(pools[pool].requested, requests)
+		}
+	}
+
+	capacity := func(name string, t *totals) PoolCapacity {
+		result := PoolCapacity{Pool: name, Nodes: t.nodes, Resources: make(map[v1.ResourceName]ResourceHeadroom)}
+		for _, resourceName := range capacityResources {
+			allocatable, requested := t.allocatable[resourceName], t.requested[resourceName]
+			headroom := ResourceHeadroom{
+				Allocatable: allocatable.String(),
+				Requested:   requested.String(),
+			}
+			if allocatable.MilliValue() > 0 {
+				headroom.Headroom = float64(allocatable.MilliValue()-requested.MilliValue()) / float64(allocatable.MilliValue()) * 100
+			}
+			result.Resources[resourceName] = headroom
+		}
+		return result
+	}
+
+	summary := CapacitySummary{Overall: capacity("overall", overall)}
+	for name, t := range pools {
+		summary.Pools = append(summary.Pools, capacity(name, t))
+	}
+	sort.Slice(summary.Pools, func(i, j int) bool { return summary.Pools[i].Pool < summary.Pools[j].Pool })
+	return summary
+}
+
+// podRequests returns cpu and memory requests of the pod counted by the scheduler and a single pod slot:
+// the sum of containers requests or the largest init container request, whichever is greater
+func podRequests(pod v1.Pod) v1.ResourceList {
+	requests := v1.ResourceList{v1.ResourcePods: *resource.NewQuantity(1, resource.DecimalSI)}
+	for _, container := range pod.Spec.Containers {
+		addResources(requests, container.Resources.Requests)
+	}
+
+	for _, container := range pod.Spec.InitContainers {
+		for _, resourceName := range []v1.ResourceName{v1.ResourceCPU, v1.ResourceMemory} {
+			request, ok := container.Resources.Requests[resourceName]
+			if !ok {
+				continue
+			}
+			if current := requests[resourceName]; request.Cmp(current) > 0 {
+				requests[resourceName] = request.DeepCopy()
+			}
+		}
+	}
+	return requests
+}
+
+// addResources adds cpu, memory and pods quantities to the list
+func addResources(list, add v1.ResourceList) {
+	for _, resourceName := range capacityResources {
+		quantity, ok := add[resourceName]
+		if !ok {
+			continue
+		}
+		current := list[resourceName]
+		current.Add(quantity)
+		list[resourceName] = current
+	}
+}
@@ -0,0 +1,98 @@
+package runner
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"k8s.io/api/core/v1"
+)
+
+func testCapacityNode(name, pool, cpu, memory string, ready bool) map[string]interface{} {
+	status := "True"
+	if !ready {
+		status = "False"
+	}
+	return map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Node",
+		"metadata":   map[string]interface{}{"name": name, "labels": map[string]interface{}{"pool": pool}},
+		"status": map[string]interface{}{
+			"allocatable": map[string]interface{}{"cpu": cpu, "memory": memory, "pods": "10"},
+			"conditions":  []interface{}{map[string]interface{}{"type": "Ready", "status": status}},
+		},
+	}
+}
+
+func testCapacityPod(name, node, phase, cpu, memory string) map[string]interface{} {
+	return map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Pod",
+		"metadata":   map[string]interface{}{"name": name, "namespace": "default"},
+		"spec": map[string]interface{}{
+			"nodeName": node,
+			"containers": []interface{}{map[string]interface{}{
+				"name":      "app",
+				"resources": map[string]interface{}{"requests": map[string]interface{}{"cpu": cpu, "memory": memory}},
+			}},
+		},
+		"status": map[string]interface{}{"phase": phase},
+	}
+}
+
+func TestCapacityChecker(t *testing.T) {
+	objects := []map[string]interface{}{
+		testCapacityNode("default-1", "default", "2", "8Gi", true),
+		testCapacityNode("default-2", "default", "2", "8Gi", true),
+		testCapacityNode("highmem-1", "highmem", "2", "32Gi", true),
+		// not ready nodes don't add capacity
+		testCapacityNode("highmem-2", "highmem", "2", "32Gi", false),
+		testCapacityPod("api-0", "default-1", "Running", "1800m", "1Gi"),
+		testCapacityPod("api-1", "default-2", "Running", "1900m", "1Gi"),
+		testCapacityPod("cache-0", "highmem-1", "Running", "500m", "16Gi"),
+		testCapacityPod("cache-1", "highmem-2", "Running", "500m", "16Gi"),
+		// finished and pending pods don't request resources
+		testCapacityPod("job-0", "highmem-1", "Succeeded", "1", "8Gi"),
+		testCapacityPod("api-2", "", "Pending", "1", "1Gi"),
+	}
+
+	checker, err := NewCapacityChecker(KubeConfig{Client: newFakeClient(t, objects, nil)}, "capacity", CapacityParams{
+		PoolLabel: "pool",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var probes Probes
+	checker.Check(context.Background(), &probes)
+	probe := probes.GetProbes()[0]
+	if probe.Status != ProbeFailed || probe.Severity != ProbeCritical {
+		t.Fatalf("expected critical failure, got %s %s: %s", probe.Status, probe.Severity, probe.Error)
+	}
+
+	summary := probe.CheckerData.(CapacitySummary)
+	if summary.Overall.Nodes != 3 || len(summary.Pools) != 2 {
+		t.Fatalf("unexpected summary %+v", summary)
+	}
+
+	expected := []struct {
+		pool     PoolCapacity
+		name     string
+		resource v1.ResourceName
+		headroom float64
+	}{
+		{pool: summary.Overall, name: "overall", resource: v1.ResourceCPU, headroom: 30},
+		{pool: summary.Overall, name: "overall", resource: v1.ResourceMemory, headroom: 62.5},
+		{pool: summary.Overall, name: "overall", resource: v1.ResourcePods, headroom: 90},
+		{pool: summary.Pools[0], name: "default", resource: v1.ResourceCPU, headroom: 7.5},
+		{pool: summary.Pools[1], name: "highmem", resource: v1.ResourceMemory, headroom: 50},
+	}
+	for _, e := range expected {
+		if e.pool.Pool != e.name {
+			t.Errorf("expected pool %s, got %s", e.name, e.pool.Pool)
+		}
+		if headroom := e.pool.Resources[e.resource].Headroom; math.Abs(headroom-e.headroom) > 0.01 {
+			t.Errorf("%s %s: expected headroom %.2f, got %.2f", e.name, e.resource, e.headroom, headroom)
+		}
+	}
+}
@@ -0,0 +1,313 @@
+package runner
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net"
+	"net/url"
+	"time"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+)
+
+// CertificatesParams configures the certificates expiry checker
+type CertificatesParams struct {
+	// Namespaces are namespaces of scanned kubernetes.io/tls secrets, all namespaces when empty
+	Namespaces []string `json:"namespaces"`
+	// Selector is the label selector of scanned secrets
+	Selector string `json:"selector"`
+	// SkipSecrets disables scanning of secrets
+	SkipSecrets bool `json:"skipSecrets"`
+	// Endpoints are host:port addresses whose served certificates are checked
+	Endpoints []string `json:"endpoints"`
+	// SkipAPIServer disables checking of the API server serving certificate
+	SkipAPIServer bool `json:"skipAPIServer"`
+	// SkipClientCert disables checking of the kubeconfig client certificate
+	SkipClientCert bool `json:"skipClientCert"`
+	// WarningDays is the number of days before expiry when a warning is reported, 30 by default
+	WarningDays int `json:"warningDays"`
+	// CriticalDays is the number of days before expiry when a critical failure is reported, 7 by default
+	CriticalDays int `json:"criticalDays"`
+	// Timeout is the timeout of fetching the certificate from a single endpoint
+	Timeout string `json:"timeout"`
+}
+
+// CertificateInfo describes a single certificate
+type CertificateInfo struct {
+	Subject  string    `json:"subject"`
+	SANs     []string  `json:"sans,omitempty"`
+	Issuer   string    `json:"issuer"`
+	NotAfter time.Time `json:"notAfter"`
+	DaysLeft int       `json:"daysLeft"`
+}
+
+// CertificatesStatus is the status of certificates found in a single source
+type CertificatesStatus struct {
+	// Source is the secret, endpoint, API server or kubeconfig the certificates come from
+	Source       string            `json:"source"`
+	Certificates []CertificateInfo `json:"certificates"`
+}
+
+// NewCertificatesChecker returns a Checker that reports certificates which expire soon
+func NewCertificatesChecker(config KubeConfig, name string, params CertificatesParams) (Checker, error) {
+	if params.WarningDays == 0 {
+		params.WarningDays = 30
+	}
+	if params.CriticalDays == 0 {
+		params.CriticalDays = 7
+	}
+	if params.CriticalDays > params.WarningDays {
+		return nil, fmt.Errorf("criticalDays %d is greater than warningDays %d", params.CriticalDays, params.WarningDays)
+	}
+	if len(params.Namespaces) == 0 {
+		params.Namespaces = []string{metav1.NamespaceAll}
+	}
+	for _, endpoint := range params.Endpoints {
+		if _, _, err := net.SplitHostPort(endpoint); err != nil {
+			return nil, fmt.Errorf("invalid endpoint %s: %s", endpoint, err)
+		}
+	}
+
+	timeout, err := parseTimeout(params.Timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	return &certificatesChecker{
+		name:    name,
+		config:  config,
+		params:  params,
+		timeout: timeout,
+		now:     time.Now,
+	}, nil
+}
+
+// certificatesChecker reports every source of certificates as a separate probe
+type certificatesChecker struct {
+	name    string
+	config  KubeConfig
+	params  CertificatesParams
+	timeout time.Duration
+	now     func() time.Time
+}
+
+// Name returns the name of this checker
+func (r *certificatesChecker) Name() string { return r.name }
+
+// Check collects certificates from all configured sources and validates their expiry
+func (r *certificatesChecker) Check(ctx context.Context, reporter Reporter) {
+	var probes Probes
+
+	if !r.params.SkipSecrets {
+		r.checkSecrets(&probes)
+	}
+
+	if !r.params.SkipAPIServer && r.config.RestConfig != nil {
+		address, ok, err := apiServerAddress(r.config.RestConfig)
+		switch {
+		case err != nil:
+			probes.Add(NewProbeFromErr(r.Name()+"/apiserver", "invalid API server address", err))
+		case ok:
+			certs, err := r.servedCertificates(ctx, address)
+			probes.Add(r.probe(r.Name()+"/apiserver", "API server "+address, certs, err))
+		}
+	}
+
+	if !r.params.SkipClientCert && r.config.RestConfig != nil {
+		certs, ok, err := clientCertificates(r.config.RestConfig)
+		if ok {
+			probes.Add(r.probe(r.Name()+"/kubeconfig", "kubeconfig client certificate", certs, err))
+		}
+	}
+
+	for _, endpoint := range r.params.Endpoints {
+		certs, err := r.servedCertificates(ctx, endpoint)
+		probes.Add(r.probe(fmt.Sprintf("%s/endpoint/%s", r.Name(), endpoint), "endpoint "+endpoint, certs, err))
+	}
+
+	if probes.NumProbes() == 0 {
+		reporter.Add(&Probe{
+			Checker: r.Name(),
+			Status:  ProbeRunning,
+		})
+		return
+	}
+	AddFrom(reporter, &probes)
+}
+
+// checkSecrets reports a probe for every kubernetes.io/tls secret
+func (r *certificatesChecker) checkSecrets(reporter Reporter) {
+	options := metav1.ListOptions{
+		LabelSelector: r.params.Selector,
+		FieldSelector: "type=" + string(v1.SecretTypeTLS),
+	}
+
+	for _, namespace := range r.params.Namespaces {
+		secrets, err := r.config.Client.CoreV1().Secrets(namespace).List(options)
+		if err != nil {
+			reporter.Add(NewProbeFromErr(r.Name(), "failed to query secrets", err))
+			return
+		}
+
+		for _, secret := range secrets.Items {
+			source := fmt.Sprintf("secret %s/%s", secret.Namespace, secret.Name)
+			certs, err := parseCertificates(secret.Data[v1.TLSCertKey])
+			reporter.Add(r.probe(fmt.Sprintf("%s/secret/%s/%s", r.Name(), secret.Namespace, secret.Name), source, certs, err))
+		}
+	}
+}
+
+// servedCertificates returns certificates presented by the TLS endpoint. The certificates
+// are not verified, untrusted certificates can expire as well.
+func (r *certificatesChecker) servedCertificates(ctx context.Context, address string) ([]*x509.Certificate, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+
+	dialer := &tls.Dialer{Config: &tls.Config{
+		ServerName:         host,
+		InsecureSkipVerify: true,
+	}}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	certs := conn.(*tls.Conn).ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no certificates served by %s", address)
+	}
+	return certs, nil
+}
+
+// probe returns the probe of certificates from a single source, the severity
+// depends on the certificate which expires first
+func (r *certificatesChecker) probe(checker, source string, certs []*x509.Certificate, err error) *Probe {
+	if err != nil {
+		return NewProbeFromErr(checker, fmt.Sprintf("can't read certificates of %s", source), err)
+	}
+
+	now := r.now()
+	status := CertificatesStatus{Source: source}
+	first := 0
+	for i, cert := range certs {
+		status.Certificates = append(status.Certificates, certificateInfo(cert, now))
+		if cert.NotAfter.Before(certs[first].NotAfter) {
+			first = i
+		}
+	}
+
+	expiring := status.Certificates[first]
+	probe := &Probe{
+		Checker:     checker,
+		Status:      ProbeFailed,
+		CheckerData: status,
+	}
+	switch {
+	case expiring.DaysLeft < 0:
+		probe.Severity = ProbeCritical
+		probe.Error = fmt.Sprintf("certificate %s of %s expired on %s", expiring.Subject, source, expiring.NotAfter.Format("2006-01-02"))
+	case expiring.DaysLeft <= r.params.CriticalDays:
+		probe.Severity = ProbeCritical
+		probe.Error = fmt.Sprintf("certificate %s of %s expires in %d days", expiring.Subject, source, expiring.DaysLeft)
+	case expiring.DaysLeft <= r.params.WarningDays:
+		probe.Severity = ProbeWarning
+		probe.Error = fmt.Sprintf("certificate %s of %s expires in %d days", expiring.Subject, source, expiring.DaysLeft)
+	default:
+		probe.Status = ProbeRunning
+	}
+	return probe
+}
+
+// certificateInfo describes the certificate, days left are negative for expired certificates
+func certificateInfo(cert *x509.Certificate, now time.Time) CertificateInfo {
+	info := CertificateInfo{
+		Subject:  cert.Subject.String(),
+		SANs:     cert.DNSNames,
+		Issuer:   cert.Issuer.String(),
+		NotAfter: cert.NotAfter,
+		DaysLeft: int(math.Floor(cert.NotAfter.Sub(now).Hours() / 24)),
+	}
+	for _, ip := range cert.IPAddresses {
+		info.SANs = append(info.SANs, ip.String())
+	}
+	return info
+}
+
+// parseCertificates parses all PEM encoded certificates
+func parseCertificates(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no PEM encoded certificates found")
+	}
+	return certs, nil
+}
+
+// apiServerAddress returns host:port of the API server, false when it's not served over TLS
+func apiServerAddress(config *rest.Config) (string, bool, error) {
+	host := config.Host
+	if host == "" {
+		return "", false, nil
+	}
+
+	u, err := url.Parse(host)
+	if err != nil || u.Host == "" {
+		// host without the scheme, client-go defaults it to https
+		if u, err = url.Parse("https://" + host); err != nil {
+			return "", false, err
+		}
+	}
+	if u.Scheme != "https" {
+		return "", false, nil
+	}
+
+	if u.Port() == "" {
+		return net.JoinHostPort(u.Hostname(), "443"), true, nil
+	}
+	return u.Host, true, nil
+}
+
+// clientCertificates returns the client certificates from the rest config, false when it has none
+func clientCertificates(config *rest.Config) ([]*x509.Certificate, bool, error) {
+	data := config.TLSClientConfig.CertData
+	if len(data) == 0 && config.TLSClientConfig.CertFile != "" {
+		var err error
+		if data, err = ioutil.ReadFile(config.TLSClientConfig.CertFile); err != nil {
+			return nil, true, err
+		}
+	}
+	if len(data) == 0 {
+		return nil, false, nil
+	}
+
+	certs, err := parseCertificates(data)
+	return certs, true, err
+}
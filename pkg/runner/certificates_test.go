@@ -0,0 +1,184 @@
+package runner
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"io/ioutil"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/rest"
+)
+
+func TestCertificatesCheckerSecrets(t *testing.T) {
+	objects := []map[string]interface{}{
+		tlsSecret("ingress", "valid", newTestCertificate(t, "valid.example.com", 90*24*time.Hour)),
+		tlsSecret("ingress", "expiring", newTestCertificate(t, "expiring.example.com", 20*24*time.Hour)),
+		tlsSecret("ingress", "almost-expired", newTestCertificate(t, "almost-expired.example.com", 3*24*time.Hour)),
+		tlsSecret("ingress", "expired", newTestCertificate(t, "expired.example.com", -24*time.Hour)),
+		{
+			"apiVersion": "v1",
+			"kind":       "Secret",
+			"type":       "Opaque",
+			"metadata":   map[string]interface{}{"name": "password", "namespace": "ingress"},
+		},
+	}
+
+	checker, err := NewCertificatesChecker(KubeConfig{Client: newFakeClient(t, objects, nil)}, "certs", CertificatesParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var probes Probes
+	checker.Check(context.Background(), &probes)
+
+	expected := map[string]ProbeSeverity{
+		"certs/secret/ingress/valid":          "",
+		"certs/secret/ingress/expiring":       ProbeWarning,
+		"certs/secret/ingress/almost-expired": ProbeCritical,
+		"certs/secret/ingress/expired":        ProbeCritical,
+	}
+	if probes.NumProbes() != len(expected) {
+		t.Fatalf("expected %d probes, got %d", len(expected), probes.NumProbes())
+	}
+	for _, probe := range probes.GetProbes() {
+		severity, ok := expected[probe.Checker]
+		if !ok {
+			t.Errorf("unexpected probe %s", probe.Checker)
+			continue
+		}
+		if probe.Severity != severity || (severity == "") != (probe.Status == ProbeRunning) {
+			t.Errorf("%s: expected severity %q, got %s with severity %q: %s", probe.Checker, severity, probe.Status, probe.Severity, probe.Error)
+		}
+	}
+
+	status := probes.GetProbes()[0].CheckerData.(CertificatesStatus)
+	cert := status.Certificates[0]
+	if cert.Subject != "CN=valid.example.com" || len(cert.SANs) != 1 || cert.SANs[0] != "valid.example.com" || cert.DaysLeft != 90 {
+		t.Errorf("unexpected certificate info %+v", cert)
+	}
+}
+
+func TestCertificatesCheckerEndpoints(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.TLS = &tls.Config{Certificates: []tls.Certificate{newTestKeyPair(t, "127.0.0.1", 5*24*time.Hour)}}
+	// the checker closes connections right after the handshake
+	server.Config.ErrorLog = log.New(ioutil.Discard, "", 0)
+	server.StartTLS()
+	defer server.Close()
+
+	client := newTestCertificate(t, "admin", 60*24*time.Hour)
+	config := KubeConfig{
+		Client: newFakeClient(t, nil, nil),
+		RestConfig: &rest.Config{
+			Host:            server.URL,
+			TLSClientConfig: rest.TLSClientConfig{CertData: client},
+		},
+	}
+
+	checker, err := NewCertificatesChecker(config, "certs", CertificatesParams{
+		SkipSecrets: true,
+		Endpoints:   []string{strings.TrimPrefix(server.URL, "https://")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var probes Probes
+	checker.Check(context.Background(), &probes)
+
+	statuses := make(map[string]*Probe)
+	for _, probe := range probes.GetProbes() {
+		statuses[probe.Checker] = probe
+	}
+	if len(statuses) != 3 {
+		t.Fatalf("expected API server, kubeconfig and endpoint probes, got %v", statuses)
+	}
+	if probe := statuses["certs/apiserver"]; probe == nil || probe.Severity != ProbeCritical {
+		t.Errorf("expected critical API server certificate, got %+v", probe)
+	}
+	if probe := statuses["certs/endpoint/"+strings.TrimPrefix(server.URL, "https://")]; probe == nil || probe.Severity != ProbeCritical {
+		t.Errorf("expected critical endpoint certificate, got %+v", probe)
+	}
+	if probe := statuses["certs/kubeconfig"]; probe == nil || probe.Status != ProbeRunning {
+		t.Errorf("expected valid client certificate, got %+v", probe)
+	}
+}
+
+func TestAPIServerAddress(t *testing.T) {
+	tests := []struct {
+		host    string
+		address string
+		ok      bool
+	}{
+		{host: "https://10.0.0.1", address: "10.0.0.1:443", ok: true},
+		{host: "https://api.example.com:6443", address: "api.example.com:6443", ok: true},
+		{host: "10.0.0.1:6443", address: "10.0.0.1:6443", ok: true},
+		{host: "http://localhost:8080"},
+	}
+
+	for _, test := range tests {
+		address, ok, err := apiServerAddress(&rest.Config{Host: test.host})
+		if err != nil || address != test.address || ok != test.ok {
+			t.Errorf("%s: expected %q %v, got %q %v %v", test.host, test.address, test.ok, address, ok, err)
+		}
+	}
+}
+
+func tlsSecret(namespace, name string, cert []byte) map[string]interface{} {
+	return map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Secret",
+		"type":       "kubernetes.io/tls",
+		"metadata":   map[string]interface{}{"name": name, "namespace": namespace},
+		"data": map[string]interface{}{
+			"tls.crt": base64.StdEncoding.EncodeToString(cert),
+		},
+	}
+}
+
+// newTestCertificate returns PEM encoded self-signed certificate expiring after validity
+func newTestCertificate(t *testing.T, host string, validity time.Duration) []byte {
+	pair := newTestKeyPair(t, host, validity)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: pair.Certificate[0]})
+}
+
+// newTestKeyPair returns self-signed key pair for the host expiring after validity
+func newTestKeyPair(t *testing.T, host string, validity time.Duration) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: host},
+		NotBefore:    time.Now().Add(-time.Hour * 24 * 365),
+		// an hour of margin keeps the days left stable during the test
+		NotAfter: time.Now().Add(validity + time.Hour),
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		template.IPAddresses = []net.IP{ip}
+	} else {
+		template.DNSNames = []string{host}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
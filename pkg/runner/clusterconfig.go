@@ -0,0 +1,275 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kube "k8s.io/client-go/kubernetes"
+)
+
+// clusterConfigType is the type of the cluster config checker, the main probe of the first
+// checker of this type is reported as the cluster config
+const clusterConfigType = "clusterconfig"
+
+// ConfigAssertions are assertions on data of a ConfigMap or Secret
+type ConfigAssertions struct {
+	// Required are keys which must be present
+	Required []string `json:"required"`
+	// Values are expected values of keys
+	Values map[string]string `json:"values"`
+	// Patterns are regular expressions values of keys must match
+	Patterns map[string]string `json:"patterns"`
+	// Versions are semver constraints on values of keys, i.e. cluster-version: ">=1.10, <1.13"
+	Versions map[string]string `json:"versions"`
+	// Schemas are JSON schemas of JSON or YAML documents stored in values of keys
+	Schemas map[string]map[string]interface{} `json:"schemas"`
+}
+
+// ConfigObjectParams selects a ConfigMap or Secret and assertions on its data.
+// Only keys of Secrets are checked, their values are never read.
+type ConfigObjectParams struct {
+	// Kind is ConfigMap or Secret, ConfigMap by default
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	ConfigAssertions
+}
+
+// ClusterConfigParams configures the cluster config checker. The main ConfigMap is reported
+// under the checker name, additional objects are reported as separate probes.
+type ClusterConfigParams struct {
+	ConfigObjectParams
+	// Objects are additional ConfigMaps and Secrets
+	Objects []ConfigObjectParams `json:"objects"`
+}
+
+// SecretKeys is the data reported for Secrets, it contains keys only
+type SecretKeys struct {
+	Keys []string `json:"keys"`
+}
+
+// NewClusterConfigChecker returns a Checker that validates data of ConfigMaps and keys of Secrets
+func NewClusterConfigChecker(config KubeConfig, name string, params ClusterConfigParams) (Checker, error) {
+	group := &checkerGroup{name: name}
+
+	if params.Namespace != "" || params.Name != "" {
+		checker, err := configObject(config, name, params.ConfigObjectParams)
+		if err != nil {
+			return nil, err
+		}
+		group.Checkers = append(group.Checkers, checker)
+	}
+
+	for _, object := range params.Objects {
+		if object.Kind == "" {
+			object.Kind = "ConfigMap"
+		}
+		checker, err := configObject(config, fmt.Sprintf("%s/%s/%s/%s", name, strings.ToLower(object.Kind), object.Namespace, object.Name), object)
+		if err != nil {
+			return nil, err
+		}
+		group.Checkers = append(group.Checkers, checker)
+	}
+
+	if len(group.Checkers) == 0 {
+		return nil, fmt.Errorf("namespace and name params or objects are required")
+	}
+	return group, nil
+}
+
+// configObject returns the checker of a single ConfigMap or Secret
+func configObject(config KubeConfig, name string, params ConfigObjectParams) (Checker, error) {
+	if params.Namespace == "" || params.Name == "" {
+		return nil, fmt.Errorf("namespace and name of %s are required", name)
+	}
+
+	assertions, err := params.ConfigAssertions.compile()
+	if err != nil {
+		return nil, fmt.Errorf("invalid assertions of %s/%s: %s", params.Namespace, params.Name, err)
+	}
+
+	var checker KubeStatusChecker
+	switch params.Kind {
+	case "", "ConfigMap":
+		checker = configMapData(params.Namespace, params.Name, assertions)
+	case "Secret":
+		if assertions.hasValueAssertions() {
+			return nil, fmt.Errorf("only required keys can be checked in secret %s/%s", params.Namespace, params.Name)
+		}
+		checker = secretKeys(params.Namespace, params.Name, assertions)
+	default:
+		return nil, fmt.Errorf("unsupported kind %q of %s/%s", params.Kind, params.Namespace, params.Name)
+	}
+
+	return &KubeChecker{
+		name:    name,
+		checker: checker,
+		client:  config.Client,
+	}, nil
+}
+
+// configMapData returns KubeStatusChecker which validates the ConfigMap data and reports it
+func configMapData(namespace, name string, assertions *configAssertions) KubeStatusChecker {
+	return func(ctx context.Context, client kube.Interface) (interface{}, error) {
+		res, err := client.CoreV1().ConfigMaps(namespace).Get(name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+
+		if problems := assertions.validate(res.Data); len(problems) > 0 {
+			return res.Data, fmt.Errorf("config map %s/%s: %s", namespace, name, strings.Join(problems, ", "))
+		}
+		return res.Data, nil
+	}
+}
+
+// secretKeys returns KubeStatusChecker which validates keys of the Secret and reports them
+func secretKeys(namespace, name string, assertions *configAssertions) KubeStatusChecker {
+	return func(ctx context.Context, client kube.Interface) (interface{}, error) {
+		res, err := client.CoreV1().Secrets(namespace).Get(name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+
+		// values are dropped right away, only keys are validated and reported
+		keys := SecretKeys{}
+		data := make(map[string]string, len(res.Data))
+		for key := range res.Data {
+			keys.Keys = append(keys.Keys, key)
+			data[key] = ""
+		}
+		sort.Strings(keys.Keys)
+
+		if problems := assertions.validate(data); len(problems) > 0 {
+			return keys, fmt.Errorf("secret %s/%s: %s", namespace, name, strings.Join(problems, ", "))
+		}
+		return keys, nil
+	}
+}
+
+// configAssertions are compiled ConfigAssertions
+type configAssertions struct {
+	required []string
+	values   map[string]string
+	patterns map[string]*regexp.Regexp
+	versions map[string]versionConstraint
+	schemas  map[string]jsonSchema
+}
+
+// compile parses patterns, version constraints and schemas of the assertions
+func (a ConfigAssertions) compile() (*configAssertions, error) {
+	compiled := &configAssertions{
+		required: a.Required,
+		values:   a.Values,
+		patterns: make(map[string]*regexp.Regexp),
+		versions: make(map[string]versionConstraint),
+		schemas:  make(map[string]jsonSchema),
+	}
+
+	for key, pattern := range a.Patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern of %s: %s", key, err)
+		}
+		compiled.patterns[key] = re
+	}
+	for key, constraint := range a.Versions {
+		c, err := parseVersionConstraint(constraint)
+		if err != nil {
+			return nil, fmt.Errorf("invalid version constraint of %s: %s", key, err)
+		}
+		compiled.versions[key] = c
+	}
+	for key, schema := range a.Schemas {
+		s, err := newJSONSchema(schema)
+		if err != nil {
+			return nil, fmt.Errorf("invalid schema of %s: %s", key, err)
+		}
+		compiled.schemas[key] = s
+	}
+
+	return compiled, nil
+}
+
+// hasValueAssertions returns true when values of keys are asserted
+func (a *configAssertions) hasValueAssertions() bool {
+	return len(a.values)+len(a.patterns)+len(a.versions)+len(a.schemas) > 0
+}
+
+// validate returns problems of the data, keys with value assertions are required as well
+func (a *configAssertions) validate(data map[string]string) []string {
+	var problems []string
+
+	value := func(key string) (string, bool) {
+		v, ok := data[key]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("missing key %s", key))
+		}
+		return v, ok
+	}
+
+	for _, key := range a.required {
+		value(key)
+	}
+	for _, key := range sortedKeys(a.values) {
+		if v, ok := value(key); ok && v != a.values[key] {
+			problems = append(problems, fmt.Sprintf("%s is %q, expected %q", key, v, a.values[key]))
+		}
+	}
+	for _, key := range sortedKeys(a.patterns) {
+		if v, ok := value(key); ok && !a.patterns[key].MatchString(v) {
+			problems = append(problems, fmt.Sprintf("%s is %q, doesn't match %q", key, v, a.patterns[key]))
+		}
+	}
+	for _, key := range sortedKeys(a.versions) {
+		v, ok := value(key)
+		if !ok {
+			continue
+		}
+		version, err := parseSemver(v)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %s", key, err))
+			continue
+		}
+		if !a.versions[key].check(version) {
+			problems = append(problems, fmt.Sprintf("%s %s doesn't satisfy the version constraint", key, v))
+		}
+	}
+	for _, key := range sortedKeys(a.schemas) {
+		v, ok := value(key)
+		if !ok {
+			continue
+		}
+		document, err := yaml.YAMLToJSON([]byte(v))
+		var decoded interface{}
+		if err == nil {
+			err = json.Unmarshal(document, &decoded)
+		}
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s is not JSON or YAML: %s", key, err))
+			continue
+		}
+		for _, problem := range a.schemas[key].validate(decoded) {
+			problems = append(problems, fmt.Sprintf("%s %s", key, problem))
+		}
+	}
+
+	return problems
+}
+
+// sortedKeys returns sorted keys of the map with string keys
+func sortedKeys(m interface{}) []string {
+	var keys []string
+	for _, key := range reflect.ValueOf(m).MapKeys() {
+		keys = append(keys, key.String())
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,145 @@
+package runner
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestVersionConstraint(t *testing.T) {
+	tests := []struct {
+		constraint string
+		version    string
+		satisfied  bool
+	}{
+		{constraint: ">=1.10, <1.13", version: "v1.12.7", satisfied: true},
+		{constraint: ">=1.10, <1.13", version: "1.13.0", satisfied: false},
+		{constraint: ">=1.10, <1.13", version: "1.13.0-rc.1", satisfied: true},
+		{constraint: "1.12", version: "1.12.0", satisfied: true},
+		{constraint: "!=1.12.3", version: "1.12.3", satisfied: false},
+		{constraint: "~1.12.2", version: "1.12.9", satisfied: true},
+		{constraint: "~1.12.2", version: "1.13.0-alpha", satisfied: false},
+		{constraint: "^1.2", version: "1.9.0", satisfied: true},
+		{constraint: "^1.2", version: "2.0.0", satisfied: false},
+		{constraint: "^0.2.1", version: "0.3.0", satisfied: false},
+		{constraint: "<1.0 || >=2.0", version: "2.1", satisfied: true},
+		{constraint: "<1.0 || >=2.0", version: "1.5", satisfied: false},
+		{constraint: ">1.0.0-alpha.2", version: "1.0.0-alpha.10", satisfied: true},
+		{constraint: ">1.0.0-alpha", version: "1.0.0-beta", satisfied: true},
+	}
+
+	for _, test := range tests {
+		constraint, err := parseVersionConstraint(test.constraint)
+		if err != nil {
+			t.Fatalf("%s: %s", test.constraint, err)
+		}
+		version, err := parseSemver(test.version)
+		if err != nil {
+			t.Fatalf("%s: %s", test.version, err)
+		}
+		if satisfied := constraint.check(version); satisfied != test.satisfied {
+			t.Errorf("%s %s: expected %v, got %v", test.version, test.constraint, test.satisfied, satisfied)
+		}
+	}
+}
+
+func TestJSONSchema(t *testing.T) {
+	var schema map[string]interface{}
+	if err := json.Unmarshal([]byte(`{
+		"type": "object",
+		"required": ["replicas", "zones"],
+		"additionalProperties": false,
+		"properties": {
+			"replicas": {"type": "integer", "minimum": 1, "maximum": 5},
+			"zones": {"type": "array", "minItems": 1, "items": {"type": "string", "pattern": "^eu-"}},
+			"mode": {"enum": ["active", "standby"]}
+		}
+	}`), &schema); err != nil {
+		t.Fatal(err)
+	}
+
+	compiled, err := newJSONSchema(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		document string
+		problems []string
+	}{
+		{document: `{"replicas": 3, "zones": ["eu-west-1a"], "mode": "active"}`},
+		{
+			document: `{"replicas": 1.5, "zones": ["us-east-1a"], "mode": "passive", "extra": true}`,
+			problems: []string{
+				"$: unexpected property extra",
+				"$.mode: passive is not one of [active standby]",
+				"$.replicas: expected type integer, got number",
+				`$.zones[0]: "us-east-1a" doesn't match "^eu-"`,
+			},
+		},
+		{document: `{"zones": []}`, problems: []string{"$: missing required property replicas", "$.zones: 0 items is less than 1"}},
+		{document: `[]`, problems: []string{"$: expected type object, got array"}},
+	}
+
+	for _, test := range tests {
+		var document interface{}
+		if err := json.Unmarshal([]byte(test.document), &document); err != nil {
+			t.Fatal(err)
+		}
+		if problems := compiled.validate(document); strings.Join(problems, "\n") != strings.Join(test.problems, "\n") {
+			t.Errorf("%s: expected problems %q, got %q", test.document, test.problems, problems)
+		}
+	}
+}
+
+func TestJSONSchemaRejectsUnsupportedKeywords(t *testing.T) {
+	tests := []struct {
+		schema string
+		err    string
+	}{
+		{schema: `{"type": "string", "title": "Zone", "description": "zone of the cluster", "default": "eu-west-1a"}`},
+		{schema: `{"oneOf": [{"type": "string"}, {"type": "integer"}]}`, err: "$: unsupported keyword oneOf"},
+		{schema: `{"type": "object", "properties": {"zone": {"$ref": "#/definitions/zone"}}}`, err: "$.zone: unsupported keyword $ref"},
+		{schema: `{"type": "array", "items": {"type": "string", "format": "hostname"}}`, err: "$[]: unsupported keyword format"},
+		{schema: `{"allOf": [{"type": "object"}], "anyOf": []}`, err: "$: unsupported keyword allOf"},
+		{schema: `{"additionalProperties": {"type": "string"}}`, err: "$: additionalProperties must be a boolean"},
+		{schema: `{"items": [{"type": "string"}]}`, err: "$: items must be a schema object"},
+		{schema: `{"type": "string", "minLength": "3"}`, err: "$: minLength must be a number"},
+		{schema: `{"type": "array", "maxItems": null}`, err: "$: maxItems must be a number"},
+		{schema: `{"type": "integer", "minimum": 1, "maximum": "10"}`, err: "$: maximum must be a number"},
+		{schema: `{"type": "object", "required": "zone"}`, err: "$: required must be an array"},
+		{schema: `{"type": "object", "required": [1]}`, err: "$: required must be an array of strings"},
+		{schema: `{"enum": "eu-west-1a"}`, err: "$: enum must be an array"},
+		{schema: `{"type": "object", "properties": ["zone"]}`, err: "$: properties must be an object"},
+		{schema: `{"type": "object", "properties": {"zone": {"pattern": 1}}}`, err: "$.zone: pattern must be a string"},
+	}
+
+	for _, test := range tests {
+		var schema map[string]interface{}
+		if err := json.Unmarshal([]byte(test.schema), &schema); err != nil {
+			t.Fatal(err)
+		}
+
+		_, err := newJSONSchema(schema)
+		if test.err == "" && err != nil {
+			t.Errorf("%s: unexpected error: %s", test.schema, err)
+		}
+		if test.err != "" && (err == nil || err.Error() != test.err) {
+			t.Errorf("%s: expected error %q, got %v", test.schema, test.err, err)
+		}
+	}
+}
+
+func TestClusterConfigSecretValuesAreNotAsserted(t *testing.T) {
+	_, err := NewClusterConfigChecker(KubeConfig{Client: newFakeClient(t, nil, nil)}, "config", ClusterConfigParams{
+		Objects: []ConfigObjectParams{{
+			Kind:             "Secret",
+			Namespace:        "ava",
+			Name:             "credentials",
+			ConfigAssertions: ConfigAssertions{Values: map[string]string{"password": "secret"}},
+		}},
+	})
+	if err == nil {
+		t.Error("expected error when values of secret are asserted")
+	}
+}
@@ -0,0 +1,108 @@
+package runner
+
+import (
+	"fmt"
+	"strings"
+)
+
+// dependentChecker is a checker which runs only when all its dependencies succeeded
+type dependentChecker interface {
+	DependsOn() []string
+}
+
+// dependencies returns names of checkers the checker depends on
+func dependencies(checker Checker) []string {
+	if d, ok := checker.(dependentChecker); ok {
+		return d.DependsOn()
+	}
+	return nil
+}
+
+// checkerResult is the outcome of a single checker shared with its dependents
+type checkerResult struct {
+	// done is closed when the checker finished or was skipped
+	done chan struct{}
+	// failed is true when the checker reported a failed critical probe or was skipped
+	failed bool
+	// rootCause is the name of the first failed checker in the dependency chain
+	rootCause string
+}
+
+// blockedBy returns the root cause of the first failed dependency, it waits for all dependencies to finish
+func blockedBy(checker Checker, results map[string]*checkerResult) string {
+	var rootCause string
+	for _, dep := range dependencies(checker) {
+		result, ok := results[dep]
+		if !ok {
+			continue
+		}
+		<-result.done
+		if result.failed && rootCause == "" {
+			rootCause = result.rootCause
+		}
+	}
+	return rootCause
+}
+
+// newSkippedProbe returns a probe for the checker skipped because of failed dependency
+func newSkippedProbe(name, rootCause string) *Probe {
+	return &Probe{
+		Checker: name,
+		Status:  ProbeSkipped,
+		Detail:  fmt.Sprintf("root cause: %s", rootCause),
+		Error:   fmt.Sprintf("skipped because checker %s failed", rootCause),
+		CheckerData: map[string]string{
+			"rootCause": rootCause,
+		},
+	}
+}
+
+// validateDependencies checks that all dependencies exist and there are no cycles between checkers
+func validateDependencies(checkers Checkers) error {
+	byName := make(map[string]Checker, len(checkers))
+	for _, checker := range checkers {
+		byName[checker.Name()] = checker
+	}
+
+	for _, checker := range checkers {
+		for _, dep := range dependencies(checker) {
+			if _, ok := byName[dep]; !ok {
+				return fmt.Errorf("checker %s depends on unknown or disabled checker %s", checker.Name(), dep)
+			}
+		}
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int, len(checkers))
+
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch state[name] {
+		case visiting:
+			return fmt.Errorf("dependency cycle between checkers: %s", strings.Join(append(path, name), " -> "))
+		case visited:
+			return nil
+		}
+
+		state[name] = visiting
+		for _, dep := range dependencies(byName[name]) {
+			if err := visit(dep, append(path, name)); err != nil {
+				return err
+			}
+		}
+		state[name] = visited
+		return nil
+	}
+
+	for _, checker := range checkers {
+		if err := visit(checker.Name(), nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,245 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+// DNSParams configures the cluster DNS checker
+type DNSParams struct {
+	// Names are in-cluster names to resolve, i.e. kubernetes.default.svc
+	Names []string `json:"names"`
+	// Server is the DNS server address, the cluster DNS service IP is used when empty
+	Server string `json:"server"`
+	// ServiceNamespace is the namespace of the cluster DNS service, kube-system by default
+	ServiceNamespace string `json:"serviceNamespace"`
+	// ServiceName is the name of the cluster DNS service, kube-dns by default
+	ServiceName string `json:"serviceName"`
+	// ClusterDomain is appended to names ending with .svc, cluster.local by default
+	ClusterDomain string `json:"clusterDomain"`
+	// LatencyThreshold is the resolution latency above which a warning is reported
+	LatencyThreshold string `json:"latencyThreshold"`
+}
+
+// DNSResult is the result of resolving a single name
+type DNSResult struct {
+	Name   string `json:"name"`
+	Server string `json:"server"`
+	// Addresses are the resolved addresses
+	Addresses []string `json:"addresses"`
+	// Expected are addresses of the service backing the name, empty when the name is not a service
+	Expected []string `json:"expected,omitempty"`
+	// Latency is the resolution latency
+	Latency string `json:"latency"`
+}
+
+// NewDNSChecker returns a Checker that resolves in-cluster names against the cluster DNS
+func NewDNSChecker(config KubeConfig, name string, params DNSParams) (Checker, error) {
+	if len(params.Names) == 0 {
+		params.Names = []string{"kubernetes.default.svc"}
+	}
+	if params.ServiceNamespace == "" {
+		params.ServiceNamespace = "kube-system"
+	}
+	if params.ServiceName == "" {
+		params.ServiceName = "kube-dns"
+	}
+	if params.ClusterDomain == "" {
+		params.ClusterDomain = "cluster.local"
+	}
+
+	var latencyThreshold time.Duration
+	if params.LatencyThreshold != "" {
+		var err error
+		if latencyThreshold, err = time.ParseDuration(params.LatencyThreshold); err != nil {
+			return nil, fmt.Errorf("invalid latency threshold: %s", err)
+		}
+	}
+
+	return &dnsChecker{
+		name:             name,
+		client:           config.Client.CoreV1(),
+		params:           params,
+		latencyThreshold: latencyThreshold,
+	}, nil
+}
+
+// dnsChecker resolves names against the cluster DNS and compares results with the backing services
+type dnsChecker struct {
+	name             string
+	client           corev1.CoreV1Interface
+	params           DNSParams
+	latencyThreshold time.Duration
+}
+
+// Name returns the name of this checker
+func (r *dnsChecker) Name() string { return r.name }
+
+// Check resolves all configured names and reports a probe for each of them
+func (r *dnsChecker) Check(ctx context.Context, reporter Reporter) {
+	server := r.params.Server
+	if server == "" {
+		var err error
+		if server, err = r.clusterDNSServer(); err != nil {
+			reporter.Add(NewProbeFromErr(r.Name(), "failed to find cluster DNS service", err))
+			return
+		}
+	}
+
+	resolver := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, network, server)
+		},
+	}
+
+	for _, name := range r.params.Names {
+		reporter.Add(r.resolve(ctx, resolver, server, name))
+	}
+}
+
+// clusterDNSServer returns the address of the cluster DNS service, the service must have ready endpoints
+func (r *dnsChecker) clusterDNSServer() (string, error) {
+	service, err := r.client.Services(r.params.ServiceNamespace).Get(r.params.ServiceName, metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+
+	endpoints, err := r.client.Endpoints(r.params.ServiceNamespace).Get(r.params.ServiceName, metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+	if len(readyAddresses(endpoints)) == 0 {
+		return "", fmt.Errorf("service %s/%s has no ready endpoints", r.params.ServiceNamespace, r.params.ServiceName)
+	}
+
+	return net.JoinHostPort(service.Spec.ClusterIP, "53"), nil
+}
+
+// resolve resolves the name and compares the result with addresses of the service backing it
+func (r *dnsChecker) resolve(ctx context.Context, resolver *net.Resolver, server, name string) *Probe {
+	checker := fmt.Sprintf("%s/%s", r.Name(), name)
+	result := DNSResult{Name: name, Server: server}
+
+	start := time.Now()
+	addresses, err := resolver.LookupHost(ctx, r.fqdn(name))
+	latency := time.Since(start)
+	result.Latency = latency.String()
+	if err != nil {
+		return &Probe{
+			Checker:     checker,
+			Status:      ProbeFailed,
+			Error:       fmt.Sprintf("can't resolve %s: %s", name, err),
+			CheckerData: result,
+		}
+	}
+	sort.Strings(addresses)
+	result.Addresses = addresses
+
+	expected, err := r.serviceAddresses(name)
+	if err != nil {
+		return &Probe{
+			Checker:     checker,
+			Status:      ProbeFailed,
+			Error:       fmt.Sprintf("can't get service of %s: %s", name, err),
+			CheckerData: result,
+		}
+	}
+	result.Expected = expected
+
+	if expected != nil && strings.Join(expected, ",") != strings.Join(addresses, ",") {
+		return &Probe{
+			Checker: checker,
+			Status:  ProbeFailed,
+			Error: fmt.Sprintf("%s resolved to %s, expected %s", name,
+				strings.Join(addresses, ", "), strings.Join(expected, ", ")),
+			CheckerData: result,
+		}
+	}
+
+	if r.latencyThreshold > 0 && latency > r.latencyThreshold {
+		return &Probe{
+			Checker:     checker,
+			Status:      ProbeFailed,
+			Severity:    ProbeWarning,
+			Error:       fmt.Sprintf("resolving %s took %s (threshold %s)", name, latency, r.latencyThreshold),
+			CheckerData: result,
+		}
+	}
+
+	return &Probe{
+		Checker:     checker,
+		Status:      ProbeRunning,
+		CheckerData: result,
+	}
+}
+
+// fqdn returns the fully qualified name, service names ending with .svc get the cluster domain
+func (r *dnsChecker) fqdn(name string) string {
+	if strings.HasSuffix(name, ".svc") {
+		name = name + "." + r.params.ClusterDomain
+	}
+	if !strings.HasSuffix(name, ".") {
+		name = name + "."
+	}
+	return name
+}
+
+// serviceAddresses returns sorted addresses the service name should resolve to: the cluster IP
+// or ready endpoints of a headless service. It returns nil for names which are not services.
+func (r *dnsChecker) serviceAddresses(name string) ([]string, error) {
+	serviceName, namespace, ok := r.parseServiceName(name)
+	if !ok {
+		return nil, nil
+	}
+
+	service, err := r.client.Services(namespace).Get(serviceName, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case service.Spec.Type == v1.ServiceTypeExternalName:
+		return nil, nil
+	case service.Spec.ClusterIP != v1.ClusterIPNone:
+		return []string{service.Spec.ClusterIP}, nil
+	}
+
+	endpoints, err := r.client.Endpoints(namespace).Get(serviceName, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	addresses := readyAddresses(endpoints)
+	sort.Strings(addresses)
+	return addresses, nil
+}
+
+// parseServiceName parses names like <service>.<namespace>.svc[.<cluster domain>]
+func (r *dnsChecker) parseServiceName(name string) (string, string, bool) {
+	name = strings.TrimSuffix(strings.TrimSuffix(name, "."), "."+r.params.ClusterDomain)
+	parts := strings.Split(name, ".")
+	if len(parts) != 3 || parts[2] != "svc" {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
+
+// readyAddresses returns IPs of all ready addresses of endpoints
+func readyAddresses(endpoints *v1.Endpoints) []string {
+	var addresses []string
+	for _, subset := range endpoints.Subsets {
+		for _, address := range subset.Addresses {
+			addresses = append(addresses, address.IP)
+		}
+	}
+	return addresses
+}
@@ -0,0 +1,132 @@
+package runner
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"strings"
+	"testing"
+)
+
+// startStubDNS starts UDP DNS server answering A queries from records and returns its address
+func startStubDNS(t *testing.T, records map[string]string) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if resp := stubDNSResponse(buf[:n], records); resp != nil {
+				conn.WriteTo(resp, addr)
+			}
+		}
+	}()
+
+	return conn.LocalAddr().String()
+}
+
+// stubDNSResponse builds the response with a single A record, other query types get empty answers
+func stubDNSResponse(query []byte, records map[string]string) []byte {
+	if len(query) < 12 {
+		return nil
+	}
+
+	// read the question name
+	var labels []string
+	offset := 12
+	for offset < len(query) && query[offset] != 0 {
+		length := int(query[offset])
+		labels = append(labels, string(query[offset+1:offset+1+length]))
+		offset += length + 1
+	}
+	questionEnd := offset + 5
+	if questionEnd > len(query) {
+		return nil
+	}
+	name := strings.Join(labels, ".") + "."
+	qtype := binary.BigEndian.Uint16(query[offset+1:])
+
+	resp := make([]byte, 12, 512)
+	copy(resp, query[:2])
+	binary.BigEndian.PutUint16(resp[4:], 1)
+	resp = append(resp, query[12:questionEnd]...)
+
+	ip, ok := records[name]
+	switch {
+	case !ok:
+		binary.BigEndian.PutUint16(resp[2:], 0x8183) // NXDOMAIN
+	case qtype == 1:
+		binary.BigEndian.PutUint16(resp[2:], 0x8180)
+		binary.BigEndian.PutUint16(resp[6:], 1)
+		resp = append(resp, 0xc0, 0x0c, 0, 1, 0, 1, 0, 0, 0, 30, 0, 4)
+		resp = append(resp, net.ParseIP(ip).To4()...)
+	default:
+		binary.BigEndian.PutUint16(resp[2:], 0x8180)
+	}
+	return resp
+}
+
+func TestDNSChecker(t *testing.T) {
+	server := startStubDNS(t, map[string]string{
+		"kubernetes.default.svc.cluster.local.": "10.96.0.1",
+		"api.shop.svc.cluster.local.":           "10.96.0.99",
+		"db.shop.svc.cluster.local.":            "10.244.1.5",
+	})
+
+	objects := []map[string]interface{}{
+		testService("default", "kubernetes", "10.96.0.1"),
+		testService("shop", "api", "10.96.0.20"),
+		testService("shop", "db", "None"),
+		{
+			"apiVersion": "v1",
+			"kind":       "Endpoints",
+			"metadata":   map[string]interface{}{"name": "db", "namespace": "shop"},
+			"subsets": []interface{}{map[string]interface{}{
+				"addresses": []interface{}{map[string]interface{}{"ip": "10.244.1.5"}},
+			}},
+		},
+	}
+
+	checker, err := NewDNSChecker(KubeConfig{Client: newFakeClient(t, objects, nil)}, "dns", DNSParams{
+		Server: server,
+		Names:  []string{"kubernetes.default.svc", "api.shop.svc", "db.shop.svc", "missing.shop.svc"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var probes Probes
+	checker.Check(context.Background(), &probes)
+
+	expected := map[string]ProbeType{
+		"dns/kubernetes.default.svc": ProbeRunning,
+		"dns/api.shop.svc":           ProbeFailed,
+		"dns/db.shop.svc":            ProbeRunning,
+		"dns/missing.shop.svc":       ProbeFailed,
+	}
+	for _, probe := range probes.GetProbes() {
+		if expected[probe.Checker] != probe.Status {
+			t.Errorf("%s: expected %s, got %s: %s", probe.Checker, expected[probe.Checker], probe.Status, probe.Error)
+		}
+		delete(expected, probe.Checker)
+	}
+	if len(expected) > 0 {
+		t.Errorf("missing probes: %v", expected)
+	}
+}
+
+func testService(namespace, name, clusterIP string) map[string]interface{} {
+	return map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Service",
+		"metadata":   map[string]interface{}{"name": name, "namespace": namespace},
+		"spec":       map[string]interface{}{"clusterIP": clusterIP},
+	}
+}
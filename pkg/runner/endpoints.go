@@ -0,0 +1,261 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kube "k8s.io/client-go/kubernetes"
+)
+
+// ServiceEndpointsParams configures the endpoints check of a single service
+type ServiceEndpointsParams struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	// MinReady is the minimal number of ready endpoints, 1 by default
+	MinReady int `json:"minReady"`
+	// MaxNotReady is the maximal number of not ready endpoints, not limited when not set
+	MaxNotReady *int `json:"maxNotReady"`
+	// NotReadyTimeout is the time after which a not ready endpoint is stuck and fails the check, 5m by default.
+	// Endpoints which are not ready for a shorter time, i.e. during rolling updates, don't fail it.
+	NotReadyTimeout string `json:"notReadyTimeout"`
+	// Probe is tcp or http to dial each ready endpoint, endpoints are not dialed when empty
+	Probe string `json:"probe"`
+	// Port is the name or number of the endpoint port to dial, the first port by default
+	Port string `json:"port"`
+	// Path is the path of HTTP probe, / by default
+	Path string `json:"path"`
+	// Timeout is the timeout of dialing a single endpoint, 2s by default. Endpoints are dialed
+	// concurrently, the timeout is bounded by the timeout of the checker.
+	Timeout string `json:"timeout"`
+}
+
+// ServiceEndpoints is the endpoints status of a single service
+type ServiceEndpoints struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	// Ready is the number of ready endpoints, only reachable ones when endpoints are dialed
+	Ready    int `json:"ready"`
+	MinReady int `json:"minReady"`
+	// NotReady are not ready addresses of the service
+	NotReady []string `json:"notReady,omitempty"`
+	// Stuck are addresses which are not ready longer than the not ready timeout
+	Stuck []string `json:"stuck,omitempty"`
+	// Unreachable are ready addresses which failed the probe
+	Unreachable map[string]string `json:"unreachable,omitempty"`
+}
+
+// NewServiceEndpointsChecker returns a Checker that verifies services are backed by ready endpoints
+func NewServiceEndpointsChecker(config KubeConfig, name string, services []ServiceEndpointsParams) (Checker, error) {
+	// services are checked concurrently, so slow endpoints of one service don't delay the others
+	group := &checkerGroup{name: name, parallel: true}
+	for _, params := range services {
+		if params.Namespace == "" || params.Name == "" {
+			return nil, fmt.Errorf("namespace and name of service are required")
+		}
+		if params.MinReady == 0 {
+			params.MinReady = 1
+		}
+		if params.MaxNotReady != nil && *params.MaxNotReady < 0 {
+			return nil, fmt.Errorf("negative maxNotReady of service %s/%s", params.Namespace, params.Name)
+		}
+		if !strings.HasPrefix(params.Path, "/") {
+			params.Path = "/" + params.Path
+		}
+		switch params.Probe {
+		case "", "tcp", "http":
+		default:
+			return nil, fmt.Errorf("unknown probe %q of service %s/%s", params.Probe, params.Namespace, params.Name)
+		}
+
+		timeout := 2 * time.Second
+		if params.Timeout != "" {
+			var err error
+			if timeout, err = time.ParseDuration(params.Timeout); err != nil {
+				return nil, fmt.Errorf("invalid timeout of service %s/%s: %s", params.Namespace, params.Name, err)
+			}
+		}
+
+		notReady := &notReadyTracker{timeout: 5 * time.Minute, now: time.Now}
+		if params.NotReadyTimeout != "" {
+			var err error
+			if notReady.timeout, err = time.ParseDuration(params.NotReadyTimeout); err != nil {
+				return nil, fmt.Errorf("invalid not ready timeout of service %s/%s: %s", params.Namespace, params.Name, err)
+			}
+		}
+
+		group.Checkers = append(group.Checkers, &KubeChecker{
+			name:    fmt.Sprintf("%s/%s/%s", name, params.Namespace, params.Name),
+			checker: serviceEndpoints(params, timeout, notReady),
+			client:  config.Client,
+		})
+	}
+
+	return group, nil
+}
+
+// notReadyTracker remembers since when addresses of a service are not ready across runs of the checker
+type notReadyTracker struct {
+	timeout time.Duration
+	now     func() time.Time
+
+	mu    sync.Mutex
+	since map[string]time.Time
+}
+
+// stuck records not ready addresses and returns the ones which are not ready longer than the timeout,
+// addresses which are not reported as not ready anymore are forgotten
+func (t *notReadyTracker) stuck(addresses []string) []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	since := make(map[string]time.Time, len(addresses))
+	var stuck []string
+	for _, address := range addresses {
+		first, ok := t.since[address]
+		if !ok {
+			first = now
+		}
+		since[address] = first
+		if now.Sub(first) >= t.timeout {
+			stuck = append(stuck, address)
+		}
+	}
+	t.since = since
+	return stuck
+}
+
+// serviceEndpoints returns KubeStatusChecker validating endpoints of the service
+func serviceEndpoints(params ServiceEndpointsParams, timeout time.Duration, notReady *notReadyTracker) KubeStatusChecker {
+	return func(ctx context.Context, client kube.Interface) (interface{}, error) {
+		endpoints, err := client.CoreV1().Endpoints(params.Namespace).Get(params.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+
+		status := ServiceEndpoints{
+			Namespace: params.Namespace,
+			Name:      params.Name,
+			MinReady:  params.MinReady,
+		}
+
+		var targets []string
+		for _, subset := range endpoints.Subsets {
+			port, ok := endpointPort(subset.Ports, params.Port)
+			for _, address := range subset.NotReadyAddresses {
+				status.NotReady = append(status.NotReady, address.IP)
+			}
+			for _, address := range subset.Addresses {
+				if params.Probe == "" {
+					status.Ready++
+					continue
+				}
+				if !ok {
+					return status, fmt.Errorf("service %s/%s has no port %q", params.Namespace, params.Name, params.Port)
+				}
+				targets = append(targets, net.JoinHostPort(address.IP, strconv.Itoa(int(port))))
+			}
+		}
+
+		for target, err := range probeEndpoints(ctx, params, targets, timeout) {
+			if err == nil {
+				status.Ready++
+				continue
+			}
+			if status.Unreachable == nil {
+				status.Unreachable = make(map[string]string)
+			}
+			status.Unreachable[target] = err.Error()
+		}
+		status.Stuck = notReady.stuck(status.NotReady)
+
+		var problems []string
+		if status.Ready < params.MinReady {
+			problems = append(problems, fmt.Sprintf("%d ready endpoints (minimum %d)", status.Ready, params.MinReady))
+		}
+		if params.MaxNotReady != nil && len(status.NotReady) > *params.MaxNotReady {
+			problems = append(problems, fmt.Sprintf("%d not ready endpoints (maximum %d)", len(status.NotReady), *params.MaxNotReady))
+		}
+		if len(status.Stuck) > 0 {
+			problems = append(problems, fmt.Sprintf("%d endpoints not ready for %s", len(status.Stuck), notReady.timeout))
+		}
+		if len(problems) > 0 {
+			return status, fmt.Errorf("service %s/%s: %s", params.Namespace, params.Name, strings.Join(problems, ", "))
+		}
+
+		return status, nil
+	}
+}
+
+// endpointPort returns the port with the given name or number, or the first port when not set
+func endpointPort(ports []v1.EndpointPort, port string) (int32, bool) {
+	for _, p := range ports {
+		if port == "" || p.Name == port || strconv.Itoa(int(p.Port)) == port {
+			return p.Port, true
+		}
+	}
+	return 0, false
+}
+
+// probeEndpoints probes all targets concurrently and returns errors of probes by targets
+func probeEndpoints(ctx context.Context, params ServiceEndpointsParams, targets []string, timeout time.Duration) map[string]error {
+	errs := make([]error, len(targets))
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		wg.Add(1)
+		go func(i int, target string) {
+			defer wg.Done()
+			errs[i] = probeEndpoint(ctx, params, target, timeout)
+		}(i, target)
+	}
+	wg.Wait()
+
+	results := make(map[string]error, len(targets))
+	for i, target := range targets {
+		results[target] = errs[i]
+	}
+	return results
+}
+
+// endpointClient doesn't follow redirects, so redirects of endpoints are checked as they are
+var endpointClient = &http.Client{
+	CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+}
+
+// probeEndpoint dials the endpoint over TCP or sends HTTP GET which must return 2xx or 3xx
+func probeEndpoint(ctx context.Context, params ServiceEndpointsParams, target string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if params.Probe == "tcp" {
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, "tcp", target)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("http://%s%s", target, params.Path), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := endpointClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,228 @@
+package runner
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestServiceEndpointsCheckerDialsReadyAddresses(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	port := listener.Addr().(*net.TCPAddr).Port
+
+	// 127.0.0.2 doesn't listen on the port, so only one of two ready endpoints is reachable
+	objects := []map[string]interface{}{{
+		"apiVersion": "v1",
+		"kind":       "Endpoints",
+		"metadata":   map[string]interface{}{"name": "api", "namespace": "shop"},
+		"subsets": []interface{}{map[string]interface{}{
+			"addresses": []interface{}{
+				map[string]interface{}{"ip": "127.0.0.1"},
+				map[string]interface{}{"ip": "127.0.0.2"},
+			},
+			"ports": []interface{}{map[string]interface{}{"name": "http", "port": port}},
+		}},
+	}}
+
+	tests := []struct {
+		minReady int
+		status   ProbeType
+	}{
+		{minReady: 1, status: ProbeRunning},
+		{minReady: 2, status: ProbeFailed},
+	}
+
+	for _, test := range tests {
+		checker, err := NewServiceEndpointsChecker(KubeConfig{Client: newFakeClient(t, objects, nil)}, "endpoints", []ServiceEndpointsParams{{
+			Namespace: "shop",
+			Name:      "api",
+			MinReady:  test.minReady,
+			Probe:     "tcp",
+			Port:      "http",
+			Timeout:   "500ms",
+		}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var probes Probes
+		checker.Check(context.Background(), &probes)
+		if probes.NumProbes() != 1 {
+			t.Fatalf("expected one probe, got %d", probes.NumProbes())
+		}
+
+		probe := probes.GetProbes()[0]
+		if probe.Status != test.status {
+			t.Errorf("minReady %d: expected %s, got %s: %s", test.minReady, test.status, probe.Status, probe.Error)
+		}
+		status := probe.CheckerData.(ServiceEndpoints)
+		if status.Ready != 1 || len(status.Unreachable) != 1 {
+			t.Errorf("expected one reachable and one unreachable endpoint, got %+v", status)
+		}
+		if _, ok := status.Unreachable[net.JoinHostPort("127.0.0.2", strconv.Itoa(port))]; !ok {
+			t.Errorf("expected 127.0.0.2 to be unreachable, got %v", status.Unreachable)
+		}
+	}
+}
+
+func TestServiceEndpointsCheckerProbesConcurrently(t *testing.T) {
+	// every endpoint hangs longer than the probe timeout
+	var subsets []interface{}
+	for i := 0; i < 4; i++ {
+		backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+		}))
+		defer backend.Close()
+
+		backendURL, err := url.Parse(backend.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		port, err := strconv.Atoi(backendURL.Port())
+		if err != nil {
+			t.Fatal(err)
+		}
+		subsets = append(subsets, map[string]interface{}{
+			"addresses": []interface{}{map[string]interface{}{"ip": "127.0.0.1"}},
+			"ports":     []interface{}{map[string]interface{}{"port": port}},
+		})
+	}
+
+	var objects []map[string]interface{}
+	var services []ServiceEndpointsParams
+	for _, name := range []string{"api", "web"} {
+		objects = append(objects, map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Endpoints",
+			"metadata":   map[string]interface{}{"name": name, "namespace": "shop"},
+			"subsets":    subsets,
+		})
+		services = append(services, ServiceEndpointsParams{Namespace: "shop", Name: name, Probe: "http", Timeout: "200ms"})
+	}
+
+	checker, err := NewServiceEndpointsChecker(KubeConfig{Client: newFakeClient(t, objects, nil)}, "endpoints", services)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var probes Probes
+	start := time.Now()
+	checker.Check(context.Background(), &probes)
+	// dialing 8 endpoints one after another would take 1.6s
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected endpoints to be probed concurrently, took %s", elapsed)
+	}
+
+	if probes.NumProbes() != 2 {
+		t.Fatalf("expected probe of every service, got %d", probes.NumProbes())
+	}
+	for i, probe := range probes.GetProbes() {
+		if expected := "endpoints/shop/" + services[i].Name; probe.Checker != expected {
+			t.Errorf("expected probes in order of services, got %s instead of %s", probe.Checker, expected)
+		}
+		if status := probe.CheckerData.(ServiceEndpoints); probe.Status != ProbeFailed || len(status.Unreachable) != 4 {
+			t.Errorf("expected all endpoints of %s to be unreachable, got %s %+v", probe.Checker, probe.Status, status)
+		}
+	}
+}
+
+func TestServiceEndpointsFailsStuckNotReadyAddresses(t *testing.T) {
+	objects := []map[string]interface{}{{
+		"apiVersion": "v1",
+		"kind":       "Endpoints",
+		"metadata":   map[string]interface{}{"name": "api", "namespace": "shop"},
+		"subsets": []interface{}{map[string]interface{}{
+			"addresses":         []interface{}{map[string]interface{}{"ip": "10.244.1.10"}},
+			"notReadyAddresses": []interface{}{map[string]interface{}{"ip": "10.244.1.11"}},
+			"ports":             []interface{}{map[string]interface{}{"port": 8080}},
+		}},
+	}}
+	client := newFakeClient(t, objects, nil)
+
+	now := time.Now()
+	notReady := &notReadyTracker{timeout: 5 * time.Minute, now: func() time.Time { return now }}
+	check := serviceEndpoints(ServiceEndpointsParams{Namespace: "shop", Name: "api", MinReady: 1}, time.Second, notReady)
+
+	tests := []struct {
+		after time.Duration
+		stuck bool
+	}{
+		{after: 0, stuck: false},
+		{after: 4 * time.Minute, stuck: false},
+		{after: time.Minute, stuck: true},
+	}
+
+	for i, test := range tests {
+		now = now.Add(test.after)
+		data, err := check(context.Background(), client)
+		if stuck := err != nil; stuck != test.stuck {
+			t.Errorf("run %d: expected stuck %v, got error %v", i, test.stuck, err)
+		}
+		if status := data.(ServiceEndpoints); len(status.Stuck) > 0 != test.stuck {
+			t.Errorf("run %d: unexpected stuck addresses %v", i, status.Stuck)
+		}
+	}
+
+	// the address which became ready is forgotten
+	if stuck := notReady.stuck(nil); len(stuck) != 0 || len(notReady.since) != 0 {
+		t.Errorf("expected ready addresses to be forgotten, got %v", notReady.since)
+	}
+}
+
+func TestServiceEndpointsHTTPProbeDoesNotFollowRedirects(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/healthz" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		http.Redirect(w, r, "/broken", http.StatusFound)
+	}))
+	defer backend.Close()
+
+	backendURL, err := url.Parse(backend.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, err := strconv.Atoi(backendURL.Port())
+	if err != nil {
+		t.Fatal(err)
+	}
+	objects := []map[string]interface{}{{
+		"apiVersion": "v1",
+		"kind":       "Endpoints",
+		"metadata":   map[string]interface{}{"name": "api", "namespace": "shop"},
+		"subsets": []interface{}{map[string]interface{}{
+			"addresses": []interface{}{map[string]interface{}{"ip": "127.0.0.1"}},
+			"ports":     []interface{}{map[string]interface{}{"port": port}},
+		}},
+	}}
+
+	// the path without the leading slash is probed as /healthz, its redirect passes the probe
+	checker, err := NewServiceEndpointsChecker(KubeConfig{Client: newFakeClient(t, objects, nil)}, "endpoints", []ServiceEndpointsParams{{
+		Namespace: "shop",
+		Name:      "api",
+		Probe:     "http",
+		Path:      "healthz",
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var probes Probes
+	checker.Check(context.Background(), &probes)
+	if probe := probes.GetProbes()[0]; probe.Status != ProbeRunning {
+		t.Errorf("expected redirecting endpoint to be ready, got %s: %s", probe.Status, probe.Error)
+	}
+}
@@ -0,0 +1,405 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kube "k8s.io/client-go/kubernetes"
+)
+
+// EventsParams configures the events checker
+type EventsParams struct {
+	// Namespaces are namespaces of scanned events, all namespaces when empty
+	Namespaces []string `json:"namespaces"`
+	// API is core to read core/v1 events or events to read events.k8s.io events, core by default
+	API string `json:"api"`
+	// Window is the sliding window of counted events, 15m by default. Counts of events are lifetime
+	// counts, so occurrences before the window are subtracted using counts observed by earlier runs.
+	// Events which started before the window and weren't observed before it count as a single occurrence.
+	Window string `json:"window"`
+	// Reasons are rules for warning event reasons, counted per reason and involved object kind
+	Reasons map[string]EventRule `json:"reasons"`
+	// TopOffenders is the number of objects with most events reported per group, 5 by default
+	TopOffenders int `json:"topOffenders"`
+}
+
+// EventRule defines how many warning events with a reason fail the events checker
+type EventRule struct {
+	// Threshold is the number of events within the window which fails the checker, 0 disables the rule
+	Threshold int32 `json:"threshold"`
+	// Severity is the severity of the probe when the rule fails, warning by default
+	Severity ProbeSeverity `json:"severity"`
+}
+
+// defaultEventRules returns rules for the reasons which usually precede outages
+func defaultEventRules() map[string]EventRule {
+	return map[string]EventRule{
+		"FailedScheduling": {Threshold: 10, Severity: ProbeWarning},
+		"FailedMount":      {Threshold: 5, Severity: ProbeWarning},
+		"NodeNotReady":     {Threshold: 1, Severity: ProbeWarning},
+		"Evicted":          {Threshold: 5, Severity: ProbeWarning},
+	}
+}
+
+// EventGroup are warning events with the same reason and involved object kind
+type EventGroup struct {
+	Reason string `json:"reason"`
+	Kind   string `json:"kind"`
+	// Count is the number of events within the window
+	Count int32 `json:"count"`
+	// Objects is the number of involved objects
+	Objects int `json:"objects"`
+	// TopOffenders are involved objects with most events
+	TopOffenders []EventOffender `json:"topOffenders"`
+}
+
+// EventOffender is an object involved in warning events
+type EventOffender struct {
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	Count     int32  `json:"count"`
+	// Message is the message of the latest event
+	Message string `json:"message"`
+}
+
+// EventsSummary is the data reported by the events checker when no rule fails
+type EventsSummary struct {
+	Window string       `json:"window"`
+	Groups []EventGroup `json:"groups"`
+}
+
+// warningEvent is a warning event of either events API
+type warningEvent struct {
+	// id identifies the event object
+	id        string
+	reason    string
+	kind      string
+	namespace string
+	name      string
+	message   string
+	// count is the lifetime count of the event until it's counted within the window
+	count     int32
+	firstSeen time.Time
+	lastSeen  time.Time
+}
+
+// eventObservation is the lifetime count of an event observed by a run of the checker
+type eventObservation struct {
+	at    time.Time
+	count int32
+}
+
+// NewEventsChecker returns a Checker that aggregates warning events within the sliding window
+func NewEventsChecker(config KubeConfig, name string, params EventsParams) (Checker, error) {
+	window := 15 * time.Minute
+	if params.Window != "" {
+		var err error
+		if window, err = time.ParseDuration(params.Window); err != nil {
+			return nil, fmt.Errorf("invalid window: %s", err)
+		}
+	}
+
+	switch params.API {
+	case "":
+		params.API = "core"
+	case "core", "events":
+	default:
+		return nil, fmt.Errorf("unknown events api %q", params.API)
+	}
+
+	if params.Reasons == nil {
+		params.Reasons = defaultEventRules()
+	}
+	for reason, rule := range params.Reasons {
+		switch rule.Severity {
+		case "":
+			rule.Severity = ProbeWarning
+			params.Reasons[reason] = rule
+		case ProbeWarning, ProbeCritical:
+		default:
+			return nil, fmt.Errorf("unknown severity %q of reason %s", rule.Severity, reason)
+		}
+	}
+
+	if params.TopOffenders < 0 {
+		return nil, fmt.Errorf("negative topOffenders: %d", params.TopOffenders)
+	}
+	if params.TopOffenders == 0 {
+		params.TopOffenders = 5
+	}
+	if len(params.Namespaces) == 0 {
+		params.Namespaces = []string{metav1.NamespaceAll}
+	}
+
+	return &eventsChecker{
+		name:   name,
+		client: config.Client,
+		params: params,
+		window: window,
+		now:    time.Now,
+	}, nil
+}
+
+// eventsChecker reports groups of warning events exceeding their thresholds
+type eventsChecker struct {
+	name   string
+	client kube.Interface
+	params EventsParams
+	window time.Duration
+	now    func() time.Time
+
+	mu sync.Mutex
+	// observations are counts of events observed by previous runs by event ids, the oldest first
+	observations map[string][]eventObservation
+}
+
+// Name returns the name of this checker
+func (r *eventsChecker) Name() string { return r.name }
+
+// Check counts warning events within the window and reports a probe for every failed group
+func (r *eventsChecker) Check(ctx context.Context, reporter Reporter) {
+	events, err := r.warningEvents()
+	if err != nil {
+		reporter.Add(NewProbeFromErr(r.Name(), "failed to query events", err))
+		return
+	}
+
+	groups := r.groups(events)
+	failed := false
+	for _, group := range groups {
+		rule, ok := r.params.Reasons[group.Reason]
+		if !ok || rule.Threshold == 0 || group.Count < rule.Threshold {
+			continue
+		}
+
+		failed = true
+		reporter.Add(&Probe{
+			Checker:  fmt.Sprintf("%s/%s/%s", r.Name(), group.Reason, strings.ToLower(group.Kind)),
+			Status:   ProbeFailed,
+			Severity: rule.Severity,
+			Error: fmt.Sprintf("%d %s events of %d %s objects in the last %s (threshold %d)",
+				group.Count, group.Reason, group.Objects, group.Kind, r.window, rule.Threshold),
+			CheckerData: group,
+		})
+	}
+
+	if !failed {
+		reporter.Add(&Probe{
+			Checker:     r.Name(),
+			Status:      ProbeRunning,
+			CheckerData: EventsSummary{Window: r.window.String(), Groups: groups},
+		})
+	}
+}
+
+// warningEvents returns warning events last seen within the window with counts of their occurrences within the window
+func (r *eventsChecker) warningEvents() ([]warningEvent, error) {
+	now := r.now()
+	since := now.Add(-r.window)
+
+	var all, events []warningEvent
+	for _, namespace := range r.params.Namespaces {
+		var list []warningEvent
+		var err error
+		if r.params.API == "events" {
+			list, err = r.eventsAPIEvents(namespace)
+		} else {
+			list, err = r.coreEvents(namespace)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		all = append(all, list...)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	observations := make(map[string][]eventObservation, len(all))
+	for _, event := range all {
+		observations[event.id] = append(r.since(event.id, since), eventObservation{at: now, count: event.count})
+		if event.lastSeen.After(since) {
+			event.count = r.occurrences(event, since)
+			events = append(events, event)
+		}
+	}
+	r.observations = observations
+	return events, nil
+}
+
+// occurrences returns the number of occurrences of the event within the window starting at since
+func (r *eventsChecker) occurrences(event warningEvent, since time.Time) int32 {
+	count := event.count
+	if count < 1 {
+		count = 1
+	}
+	if event.firstSeen.After(since) {
+		return count
+	}
+
+	// the count observed before the window started is the baseline, the last occurrence
+	// is within the window when there is no baseline
+	observations := r.since(event.id, since)
+	if len(observations) == 0 || observations[0].at.After(since) || count <= observations[0].count {
+		return 1
+	}
+	return count - observations[0].count
+}
+
+// since returns observations of the event needed for windows starting at since or later,
+// the latest observation before since is the first one
+func (r *eventsChecker) since(id string, since time.Time) []eventObservation {
+	observations := r.observations[id]
+	for len(observations) > 1 && !observations[1].at.After(since) {
+		observations = observations[1:]
+	}
+	return observations
+}
+
+// coreEvents returns warning events of the core/v1 API
+func (r *eventsChecker) coreEvents(namespace string) ([]warningEvent, error) {
+	list, err := r.client.CoreV1().Events(namespace).List(metav1.ListOptions{FieldSelector: "type=" + v1.EventTypeWarning})
+	if err != nil {
+		return nil, err
+	}
+
+	var events []warningEvent
+	for _, event := range list.Items {
+		if event.Type != v1.EventTypeWarning {
+			continue
+		}
+		events = append(events, warningEvent{
+			id:        event.Namespace + "/" + event.Name,
+			reason:    event.Reason,
+			kind:      event.InvolvedObject.Kind,
+			namespace: event.InvolvedObject.Namespace,
+			name:      event.InvolvedObject.Name,
+			message:   event.Message,
+			count:     event.Count,
+			firstSeen: earliest(event.FirstTimestamp.Time, event.EventTime.Time, event.CreationTimestamp.Time),
+			lastSeen:  latest(event.LastTimestamp.Time, event.EventTime.Time, event.FirstTimestamp.Time, event.CreationTimestamp.Time),
+		})
+	}
+	return events, nil
+}
+
+// eventsAPIEvents returns warning events of the events.k8s.io API
+func (r *eventsChecker) eventsAPIEvents(namespace string) ([]warningEvent, error) {
+	list, err := r.client.EventsV1beta1().Events(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	var events []warningEvent
+	for _, event := range list.Items {
+		if event.Type != v1.EventTypeWarning {
+			continue
+		}
+		warning := warningEvent{
+			id:        event.Namespace + "/" + event.Name,
+			reason:    event.Reason,
+			kind:      event.Regarding.Kind,
+			namespace: event.Regarding.Namespace,
+			name:      event.Regarding.Name,
+			message:   event.Note,
+			count:     event.DeprecatedCount,
+			firstSeen: earliest(event.EventTime.Time, event.DeprecatedFirstTimestamp.Time, event.CreationTimestamp.Time),
+			lastSeen:  latest(event.EventTime.Time, event.DeprecatedLastTimestamp.Time, event.CreationTimestamp.Time),
+		}
+		if event.Series != nil {
+			warning.count = event.Series.Count
+			warning.lastSeen = latest(warning.lastSeen, event.Series.LastObservedTime.Time)
+		}
+		events = append(events, warning)
+	}
+	return events, nil
+}
+
+// groups groups events by reason and involved object kind, the largest groups go first
+func (r *eventsChecker) groups(events []warningEvent) []EventGroup {
+	type groupKey struct{ reason, kind string }
+	type objectKey struct{ namespace, name string }
+
+	offenders := make(map[groupKey]map[objectKey]*EventOffender)
+	lastSeen := make(map[*EventOffender]time.Time)
+	for _, event := range events {
+		key := groupKey{reason: event.reason, kind: event.kind}
+		if offenders[key] == nil {
+			offenders[key] = make(map[objectKey]*EventOffender)
+		}
+
+		object := objectKey{namespace: event.namespace, name: event.name}
+		offender := offenders[key][object]
+		if offender == nil {
+			offender = &EventOffender{Namespace: event.namespace, Name: event.name}
+			offenders[key][object] = offender
+		}
+		if event.count > 0 {
+			offender.Count += event.count
+		} else {
+			offender.Count++
+		}
+		if event.lastSeen.After(lastSeen[offender]) {
+			lastSeen[offender] = event.lastSeen
+			offender.Message = event.message
+		}
+	}
+
+	var groups []EventGroup
+	for key, objects := range offenders {
+		group := EventGroup{Reason: key.reason, Kind: key.kind, Objects: len(objects)}
+		for _, offender := range objects {
+			group.Count += offender.Count
+			group.TopOffenders = append(group.TopOffenders, *offender)
+		}
+
+		sort.Slice(group.TopOffenders, func(i, j int) bool {
+			a, b := group.TopOffenders[i], group.TopOffenders[j]
+			if a.Count != b.Count {
+				return a.Count > b.Count
+			}
+			return a.Namespace+"/"+a.Name < b.Namespace+"/"+b.Name
+		})
+		if len(group.TopOffenders) > r.params.TopOffenders {
+			group.TopOffenders = group.TopOffenders[:r.params.TopOffenders]
+		}
+		groups = append(groups, group)
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Count != groups[j].Count {
+			return groups[i].Count > groups[j].Count
+		}
+		return groups[i].Reason+groups[i].Kind < groups[j].Reason+groups[j].Kind
+	})
+	return groups
+}
+
+// latest returns the latest of the times
+func latest(times ...time.Time) time.Time {
+	var result time.Time
+	for _, t := range times {
+		if t.After(result) {
+			result = t
+		}
+	}
+	return result
+}
+
+// earliest returns the earliest of the times which are set
+func earliest(times ...time.Time) time.Time {
+	var result time.Time
+	for _, t := range times {
+		if !t.IsZero() && (result.IsZero() || t.Before(result)) {
+			result = t
+		}
+	}
+	return result
+}
@@ -0,0 +1,187 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func testEvent(apiVersion, eventType, reason, kind, name string, count int, lastSeen time.Time) map[string]interface{} {
+	event := map[string]interface{}{
+		"apiVersion": apiVersion,
+		"kind":       "Event",
+		"type":       eventType,
+		"reason":     reason,
+		"metadata":   map[string]interface{}{"name": fmt.Sprintf("%s.%s", name, reason), "namespace": "shop"},
+	}
+	object := map[string]interface{}{"kind": kind, "namespace": "shop", "name": name}
+	message := fmt.Sprintf("%s of %s", reason, name)
+
+	if apiVersion == "v1" {
+		event["involvedObject"] = object
+		event["message"] = message
+		event["count"] = count
+		event["firstTimestamp"] = lastSeen.UTC().Format(time.RFC3339)
+		event["lastTimestamp"] = lastSeen.UTC().Format(time.RFC3339)
+		return event
+	}
+
+	event["regarding"] = object
+	event["note"] = message
+	event["eventTime"] = lastSeen.UTC().Format("2006-01-02T15:04:05.000000Z")
+	event["series"] = map[string]interface{}{"count": count, "lastObservedTime": lastSeen.UTC().Format("2006-01-02T15:04:05.000000Z")}
+	return event
+}
+
+func TestEventsChecker(t *testing.T) {
+	now := time.Now()
+
+	for _, api := range []string{"core", "events"} {
+		apiVersion := "v1"
+		if api == "events" {
+			apiVersion = "events.k8s.io/v1beta1"
+		}
+
+		objects := []map[string]interface{}{
+			testEvent(apiVersion, "Warning", "FailedScheduling", "Pod", "api-0", 6, now.Add(-time.Minute)),
+			testEvent(apiVersion, "Warning", "FailedScheduling", "Pod", "api-1", 4, now.Add(-2*time.Minute)),
+			testEvent(apiVersion, "Warning", "FailedScheduling", "Pod", "worker-0", 1, now.Add(-3*time.Minute)),
+			// outside of the window
+			testEvent(apiVersion, "Warning", "FailedMount", "Pod", "db-0", 20, now.Add(-time.Hour)),
+			testEvent(apiVersion, "Warning", "FailedMount", "Pod", "db-1", 2, now.Add(-time.Minute)),
+			testEvent(apiVersion, "Normal", "Scheduled", "Pod", "api-2", 30, now.Add(-time.Minute)),
+		}
+
+		checker, err := NewEventsChecker(KubeConfig{Client: newFakeClient(t, objects, nil)}, "events", EventsParams{
+			API:          api,
+			Window:       "15m",
+			TopOffenders: 2,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var probes Probes
+		checker.Check(context.Background(), &probes)
+		if probes.NumProbes() != 1 {
+			t.Fatalf("%s: expected only the FailedScheduling probe, got %d probes", api, probes.NumProbes())
+		}
+
+		probe := probes.GetProbes()[0]
+		if probe.Checker != "events/FailedScheduling/pod" || probe.Status != ProbeFailed || probe.Severity != ProbeWarning {
+			t.Errorf("%s: unexpected probe %+v", api, probe)
+		}
+
+		group := probe.CheckerData.(EventGroup)
+		if group.Count != 11 || group.Objects != 3 || len(group.TopOffenders) != 2 {
+			t.Fatalf("%s: unexpected group %+v", api, group)
+		}
+		if top := group.TopOffenders[0]; top.Name != "api-0" || top.Count != 6 || top.Message != "FailedScheduling of api-0" {
+			t.Errorf("%s: unexpected top offender %+v", api, top)
+		}
+	}
+}
+
+func TestEventsCheckerBelowThresholds(t *testing.T) {
+	now := time.Now()
+	objects := []map[string]interface{}{
+		testEvent("v1", "Warning", "FailedMount", "Pod", "db-0", 2, now.Add(-time.Minute)),
+	}
+
+	checker, err := NewEventsChecker(KubeConfig{Client: newFakeClient(t, objects, nil)}, "events", EventsParams{
+		Reasons: map[string]EventRule{"FailedMount": {Threshold: 3, Severity: ProbeCritical}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var probes Probes
+	checker.Check(context.Background(), &probes)
+	probe := probes.GetProbes()[0]
+	if probe.Status != ProbeRunning {
+		t.Fatalf("expected running probe, got %s: %s", probe.Status, probe.Error)
+	}
+	if summary := probe.CheckerData.(EventsSummary); len(summary.Groups) != 1 || summary.Groups[0].Count != 2 {
+		t.Errorf("unexpected summary %+v", summary)
+	}
+}
+
+func TestEventsCheckerCountsOccurrencesWithinWindow(t *testing.T) {
+	start := time.Now().Add(-time.Hour)
+
+	for _, api := range []string{"core", "events"} {
+		apiVersion, firstSeen := "v1", "firstTimestamp"
+		if api == "events" {
+			apiVersion, firstSeen = "events.k8s.io/v1beta1", "eventTime"
+		}
+
+		// the event started long before the window with 20 occurrences, the last one within the window
+		now := start
+		event := testEvent(apiVersion, "Warning", "FailedMount", "Pod", "db-0", 20, now.Add(-time.Minute))
+		event[firstSeen] = start.Add(-time.Hour).UTC().Format("2006-01-02T15:04:05.000000Z")
+		setCount := func(count int) {
+			if api == "events" {
+				event["series"].(map[string]interface{})["count"] = count
+				event["series"].(map[string]interface{})["lastObservedTime"] = now.Add(-time.Minute).UTC().Format("2006-01-02T15:04:05.000000Z")
+				return
+			}
+			event["count"] = count
+			event["lastTimestamp"] = now.Add(-time.Minute).UTC().Format(time.RFC3339)
+		}
+
+		checker, err := NewEventsChecker(KubeConfig{Client: newFakeClient(t, []map[string]interface{}{event}, nil)}, "events", EventsParams{
+			API:     api,
+			Window:  "15m",
+			Reasons: map[string]EventRule{"FailedMount": {Threshold: 5}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		checker.(*eventsChecker).now = func() time.Time { return now }
+
+		tests := []struct {
+			after time.Duration
+			count int
+			// counted is the number of occurrences within the window
+			counted int32
+		}{
+			// the count before the window is unknown, only the last occurrence is counted
+			{count: 20, counted: 1},
+			{after: 10 * time.Minute, count: 23, counted: 1},
+			// the first run observed 20 occurrences before the window
+			{after: 10 * time.Minute, count: 26, counted: 6},
+			// the second run observed 23 occurrences before the window
+			{after: 10 * time.Minute, count: 27, counted: 4},
+		}
+
+		for i, test := range tests {
+			now = now.Add(test.after)
+			setCount(test.count)
+
+			var probes Probes
+			checker.Check(context.Background(), &probes)
+			probe := probes.GetProbes()[0]
+
+			var count int32
+			switch data := probe.CheckerData.(type) {
+			case EventGroup:
+				count = data.Count
+			case EventsSummary:
+				count = data.Groups[0].Count
+			}
+			if count != test.counted {
+				t.Errorf("%s run %d: expected %d occurrences within the window, got %d", api, i, test.counted, count)
+			}
+			if failed := test.counted >= 5; failed != (probe.Status == ProbeFailed) {
+				t.Errorf("%s run %d: unexpected probe status %s", api, i, probe.Status)
+			}
+		}
+	}
+}
+
+func TestEventsCheckerRejectsNegativeTopOffenders(t *testing.T) {
+	if _, err := NewEventsChecker(KubeConfig{Client: newFakeClient(t, nil, nil)}, "events", EventsParams{TopOffenders: -1}); err == nil {
+		t.Error("expected error of negative topOffenders")
+	}
+}
@@ -0,0 +1,231 @@
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	kube "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// fakeResource describes how objects of a kind are served by the fake cluster
+type fakeResource struct {
+	plural     string
+	namespaced bool
+	// ignoreSelectors mirrors resources which API server lists without filtering
+	ignoreSelectors bool
+}
+
+// fakeResources maps object kinds to their API resources
+var fakeResources = map[string]fakeResource{
+	"ComponentStatus":       {plural: "componentstatuses", ignoreSelectors: true},
+	"ConfigMap":             {plural: "configmaps", namespaced: true},
+	"DaemonSet":             {plural: "daemonsets", namespaced: true},
+	"Deployment":            {plural: "deployments", namespaced: true},
+	"Endpoints":             {plural: "endpoints", namespaced: true},
+	"Event":                 {plural: "events", namespaced: true},
+	"Namespace":             {plural: "namespaces"},
+	"Node":                  {plural: "nodes"},
+	"PersistentVolume":      {plural: "persistentvolumes"},
+	"PersistentVolumeClaim": {plural: "persistentvolumeclaims", namespaced: true},
+	"Pod":                   {plural: "pods", namespaced: true},
+	"Secret":                {plural: "secrets", namespaced: true},
+	"Service":               {plural: "services", namespaced: true},
+	"StatefulSet":           {plural: "statefulsets", namespaced: true},
+	"StorageClass":          {plural: "storageclasses"},
+	"VolumeAttachment":      {plural: "volumeattachments"},
+}
+
+// fakeRaw is a response of a non-resource path like /healthz
+type fakeRaw struct {
+	Code int    `json:"code"`
+	Body string `json:"body"`
+}
+
+// fakeCluster is a minimal API server serving list and get requests from fixture objects
+type fakeCluster struct {
+	objects []map[string]interface{}
+	raw     map[string]fakeRaw
+}
+
+// newFakeClient starts the fake cluster and returns a client connected to it
+func newFakeClient(t *testing.T, objects []map[string]interface{}, raw map[string]fakeRaw) kube.Interface {
+	for _, obj := range objects {
+		if _, ok := fakeResources[str(obj, "kind")]; !ok {
+			t.Fatalf("fake cluster doesn't support kind %q", str(obj, "kind"))
+		}
+	}
+
+	srv := httptest.NewServer(&fakeCluster{objects: objects, raw: raw})
+	t.Cleanup(srv.Close)
+
+	client, err := kube.NewForConfig(&rest.Config{Host: srv.URL})
+	if err != nil {
+		t.Fatalf("can't create client. err: %s", err)
+	}
+	return client
+}
+
+func (f *fakeCluster) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if raw, ok := f.raw[r.URL.Path]; ok {
+		if raw.Code == 0 {
+			raw.Code = http.StatusOK
+		}
+		w.WriteHeader(raw.Code)
+		w.Write([]byte(raw.Body))
+		return
+	}
+
+	groupVersion, segments := splitAPIPath(r.URL.Path)
+	if groupVersion == "" {
+		notFound(w, r.URL.Path)
+		return
+	}
+
+	var namespace, plural, name string
+	switch {
+	case len(segments) >= 3 && segments[0] == "namespaces":
+		namespace, plural = segments[1], segments[2]
+		if len(segments) == 4 {
+			name = segments[3]
+		}
+	case len(segments) == 1 || len(segments) == 2:
+		plural = segments[0]
+		if len(segments) == 2 {
+			name = segments[1]
+		}
+	default:
+		notFound(w, r.URL.Path)
+		return
+	}
+
+	labelSelector, err := labels.Parse(r.URL.Query().Get("labelSelector"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	fieldSelector, err := fields.ParseSelector(r.URL.Query().Get("fieldSelector"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var kind string
+	items := make([]map[string]interface{}, 0)
+	for _, obj := range f.objects {
+		resource := fakeResources[str(obj, "kind")]
+		if resource.plural != plural || str(obj, "apiVersion") != groupVersion {
+			continue
+		}
+		kind = str(obj, "kind")
+		if namespace != "" && str(obj, "metadata", "namespace") != namespace {
+			continue
+		}
+		if name != "" && str(obj, "metadata", "name") != name {
+			continue
+		}
+		if !resource.ignoreSelectors && !matches(obj, labelSelector, fieldSelector) {
+			continue
+		}
+		items = append(items, obj)
+	}
+
+	if name != "" {
+		if len(items) == 0 {
+			notFound(w, r.URL.Path)
+			return
+		}
+		writeJSON(w, items[0])
+		return
+	}
+
+	if kind == "" {
+		for k, resource := range fakeResources {
+			if resource.plural == plural {
+				kind = k
+			}
+		}
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"apiVersion": groupVersion,
+		"kind":       kind + "List",
+		"metadata":   map[string]interface{}{},
+		"items":      items,
+	})
+}
+
+// splitAPIPath splits path like /apis/apps/v1/namespaces/ns/deployments into group version and resource segments
+func splitAPIPath(path string) (string, []string) {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	switch {
+	case len(parts) >= 3 && parts[0] == "api":
+		return parts[1], parts[2:]
+	case len(parts) >= 4 && parts[0] == "apis":
+		return parts[1] + "/" + parts[2], parts[3:]
+	}
+	return "", nil
+}
+
+// matches returns true when object matches both selectors, fields are read using their json paths
+func matches(obj map[string]interface{}, labelSelector labels.Selector, fieldSelector fields.Selector) bool {
+	objLabels := labels.Set{}
+	if l, ok := get(obj, "metadata", "labels").(map[string]interface{}); ok {
+		for k, v := range l {
+			objLabels[k] = fmt.Sprint(v)
+		}
+	}
+	if !labelSelector.Matches(objLabels) {
+		return false
+	}
+
+	objFields := fields.Set{}
+	for _, req := range fieldSelector.Requirements() {
+		objFields[req.Field] = str(obj, strings.Split(req.Field, ".")...)
+	}
+	return fieldSelector.Matches(objFields)
+}
+
+func get(obj map[string]interface{}, path ...string) interface{} {
+	var current interface{} = obj
+	for _, p := range path {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		current = m[p]
+	}
+	return current
+}
+
+func str(obj map[string]interface{}, path ...string) string {
+	value := get(obj, path...)
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprint(value)
+}
+
+func notFound(w http.ResponseWriter, path string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusNotFound)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Status",
+		"status":     "Failure",
+		"reason":     "NotFound",
+		"message":    fmt.Sprintf("%s not found", path),
+		"code":       http.StatusNotFound,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, obj interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(obj)
+}
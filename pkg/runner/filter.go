@@ -0,0 +1,184 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+)
+
+// taggedChecker is a checker which carries tags, i.e. network or control-plane
+type taggedChecker interface {
+	Tags() []string
+}
+
+// tags returns tags of the checker
+func tags(checker Checker) []string {
+	if t, ok := checker.(taggedChecker); ok {
+		return t.Tags()
+	}
+	return nil
+}
+
+// severityRank orders severities of failed probes, none is the lowest one
+var severityRank = map[ProbeSeverity]int{
+	"":            0,
+	ProbeNone:     0,
+	ProbeWarning:  1,
+	ProbeCritical: 2,
+}
+
+// Filter selects a subset of checkers and their probes. Checkers are selected when their name
+// or any of their tags is listed, all checkers are selected when neither names nor tags are set.
+type Filter struct {
+	// Checkers are names of selected checkers
+	Checkers []string
+	// Tags are tags of selected checkers
+	Tags []string
+	// Exclude are names or tags of checkers which are never selected
+	Exclude []string
+	// MinSeverity drops failed probes with lower severity, so they don't change the status.
+	// Passed and skipped probes are kept.
+	MinSeverity ProbeSeverity
+}
+
+// IsEmpty returns true when the filter selects all checkers and probes.
+// Filter with unknown severity is not empty, so it fails when it's applied.
+func (f Filter) IsEmpty() bool {
+	rank, ok := severityRank[f.MinSeverity]
+	return len(f.Checkers)+len(f.Tags)+len(f.Exclude) == 0 && ok && rank == 0
+}
+
+// matches returns true when the checker is selected by the filter
+func (f Filter) matches(checker Checker) bool {
+	checkerTags := tags(checker)
+	for _, excluded := range f.Exclude {
+		if excluded == checker.Name() || contains(checkerTags, excluded) {
+			return false
+		}
+	}
+
+	if len(f.Checkers) == 0 && len(f.Tags) == 0 {
+		return true
+	}
+	if contains(f.Checkers, checker.Name()) {
+		return true
+	}
+	for _, tag := range f.Tags {
+		if contains(checkerTags, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesSeverity returns true when the probe is kept by the minimal severity
+func (f Filter) matchesSeverity(probe *Probe) bool {
+	if probe.Status != ProbeFailed {
+		return true
+	}
+	return severityRank[failureSeverity(probe)] >= severityRank[f.MinSeverity]
+}
+
+// Subset returns the status aggregated over probes of checkers selected by the filter.
+// The result must come from Run of this runner, its check and next run times are kept.
+func (c *Runner) Subset(result *FinalProbe, filter Filter) (*FinalProbe, error) {
+	selected, err := c.selectCheckers(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	subset := c.aggregate(filterProbes(result.probes, selected, filter))
+	subset.CheckedAt, subset.Age, subset.NextRunAt = result.CheckedAt, result.Age, result.NextRunAt
+	return subset, nil
+}
+
+// RunFiltered runs only checkers selected by the filter together with their dependencies
+// and reports the status aggregated over the selected ones. Metrics are not updated
+// as they are exported for all checkers.
+func (c *Runner) RunFiltered(ctx context.Context, filter Filter) (*FinalProbe, error) {
+	selected, err := c.selectCheckers(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	probes := c.runCheckers(ctx, c.withDependencies(selected), nil)
+	return c.finalHealth(filterProbes(probes, selected, filter)), nil
+}
+
+// selectCheckers returns names of checkers selected by the filter, it fails when
+// the filter is invalid or selects no checkers
+func (c *Runner) selectCheckers(filter Filter) (map[string]bool, error) {
+	if _, ok := severityRank[filter.MinSeverity]; !ok {
+		return nil, fmt.Errorf("unknown severity: %s", filter.MinSeverity)
+	}
+
+	known := make(map[string]bool, len(c.Checkers))
+	selected := make(map[string]bool)
+	for _, checker := range c.Checkers {
+		known[checker.Name()] = true
+		if filter.matches(checker) {
+			selected[checker.Name()] = true
+		}
+	}
+
+	for _, name := range filter.Checkers {
+		if !known[name] {
+			return nil, fmt.Errorf("unknown checker: %s", name)
+		}
+	}
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("no checkers match the filter")
+	}
+	return selected, nil
+}
+
+// withDependencies returns the selected checkers and all checkers they depend on
+func (c *Runner) withDependencies(selected map[string]bool) Checkers {
+	byName := make(map[string]Checker, len(c.Checkers))
+	for _, checker := range c.Checkers {
+		byName[checker.Name()] = checker
+	}
+
+	required := make(map[string]bool)
+	var require func(name string)
+	require = func(name string) {
+		if required[name] {
+			return
+		}
+		required[name] = true
+		for _, dep := range dependencies(byName[name]) {
+			require(dep)
+		}
+	}
+	for name := range selected {
+		require(name)
+	}
+
+	var checkers Checkers
+	for _, checker := range c.Checkers {
+		if required[checker.Name()] {
+			checkers.AddChecker(checker)
+		}
+	}
+	return checkers
+}
+
+// filterProbes returns probes reported by the selected checkers with severity kept by the filter
+func filterProbes(probes []*Probe, selected map[string]bool, filter Filter) []*Probe {
+	var filtered []*Probe
+	for _, probe := range probes {
+		if selected[probe.source] && filter.matchesSeverity(probe) {
+			filtered = append(filtered, probe)
+		}
+	}
+	return filtered
+}
+
+// contains returns true when the item is in the list
+func contains(items []string, item string) bool {
+	for _, i := range items {
+		if i == item {
+			return true
+		}
+	}
+	return false
+}
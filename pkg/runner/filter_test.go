@@ -0,0 +1,99 @@
+package runner
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// tagged returns the stub checker decorated with tags, dependencies and severity like checkers from specs
+func tagged(stub *stubChecker, severity ProbeSeverity, dependsOn []string, tags ...string) Checker {
+	return &configuredChecker{Checker: stub, severity: severity, dependsOn: dependsOn, tags: tags}
+}
+
+func TestSubset(t *testing.T) {
+	runner := newStubRunner(
+		tagged(&stubChecker{name: "apiserver", status: ProbeRunning}, "", nil, "control-plane"),
+		tagged(&stubChecker{name: "dns", status: ProbeFailed}, ProbeWarning, []string{"apiserver"}, "network"),
+		tagged(&stubChecker{name: "cni", status: ProbeRunning}, "", []string{"apiserver"}, "network"),
+		tagged(&stubChecker{name: "etcd", status: ProbeFailed}, "", []string{"apiserver"}, "control-plane"),
+	)
+	result := runner.Run(context.Background())
+	if result.Status != ClusterFailed {
+		t.Fatalf("expected failed status of all checkers, got %s", result.Status)
+	}
+
+	tests := []struct {
+		name    string
+		filter  Filter
+		status  ClusterStatusType
+		checker []string
+		err     string
+	}{
+		{name: "tag", filter: Filter{Tags: []string{"network"}}, status: ClusterDegraded, checker: []string{"cni", "dns"}},
+		{name: "checker", filter: Filter{Checkers: []string{"cni", "apiserver"}}, status: ClusterHealthy, checker: []string{"apiserver", "cni"}},
+		{name: "checker or tag", filter: Filter{Checkers: []string{"etcd"}, Tags: []string{"network"}}, status: ClusterFailed, checker: []string{"cni", "dns", "etcd"}},
+		{name: "exclude", filter: Filter{Exclude: []string{"control-plane", "dns"}}, status: ClusterHealthy, checker: []string{"cni"}},
+		{name: "min severity", filter: Filter{Tags: []string{"network"}, MinSeverity: ProbeCritical}, status: ClusterHealthy, checker: []string{"cni"}},
+		{name: "unknown checker", filter: Filter{Checkers: []string{"ntp"}}, err: "unknown checker: ntp"},
+		{name: "unknown severity", filter: Filter{MinSeverity: "fatal"}, err: "unknown severity: fatal"},
+		{name: "nothing selected", filter: Filter{Tags: []string{"storage"}}, err: "no checkers match"},
+	}
+
+	for _, test := range tests {
+		subset, err := runner.Subset(result, test.filter)
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("%s: expected error %q, got %v", test.name, test.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", test.name, err)
+			continue
+		}
+
+		var checkers []string
+		for checker := range probeStatuses(subset) {
+			checkers = append(checkers, checker)
+		}
+		sort.Strings(checkers)
+		if subset.Status != test.status || !reflect.DeepEqual(checkers, test.checker) {
+			t.Errorf("%s: expected %s status of %v, got %s status of %v", test.name, test.status, test.checker, subset.Status, checkers)
+		}
+		if !subset.CheckedAt.Equal(result.CheckedAt) {
+			t.Errorf("%s: expected check time of the result, got %s", test.name, subset.CheckedAt)
+		}
+	}
+}
+
+func TestRunFilteredRunsSelectedCheckersWithDependencies(t *testing.T) {
+	apiserver := &stubChecker{name: "apiserver", status: ProbeFailed}
+	dns := &stubChecker{name: "dns", status: ProbeRunning}
+	etcd := &stubChecker{name: "etcd", status: ProbeRunning}
+	runner := newStubRunner(
+		tagged(apiserver, "", nil),
+		tagged(dns, "", []string{"apiserver"}, "network"),
+		tagged(etcd, "", []string{"apiserver"}),
+	)
+
+	result, err := runner.RunFiltered(context.Background(), Filter{Tags: []string{"network"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	runs := []int32{atomic.LoadInt32(&apiserver.runs), atomic.LoadInt32(&dns.runs), atomic.LoadInt32(&etcd.runs)}
+	if !reflect.DeepEqual(runs, []int32{1, 0, 0}) {
+		t.Errorf("expected only the dependency to run, runs of apiserver, dns and etcd: %v", runs)
+	}
+	statuses := probeStatuses(result)
+	if len(statuses) != 1 || statuses["dns"] != ProbeSkipped {
+		t.Errorf("expected only skipped dns probe, got %v", statuses)
+	}
+	if result.Status != ClusterHealthy {
+		t.Errorf("expected dependencies outside the subset to not change the status, got %s", result.Status)
+	}
+}
@@ -0,0 +1,113 @@
+package runner
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/ghodss/yaml"
+	"github.com/mateuszdyminski/k8s-status/pkg/config"
+)
+
+// fixture describes cluster objects, checkers to run against them and the expected result
+type fixture struct {
+	// Description explains the tested scenario
+	Description string `json:"description"`
+	// Checkers are checkers to run, default checkers are used when empty
+	Checkers []config.CheckerSpec `json:"checkers"`
+	// Objects are kubernetes objects served by the fake cluster
+	Objects []map[string]interface{} `json:"objects"`
+	// Raw are responses of non-resource paths like /healthz
+	Raw map[string]fakeRaw `json:"raw"`
+	// Expected is the expected final probe
+	Expected struct {
+		Status  ClusterStatusType    `json:"status"`
+		Drivers []string             `json:"drivers"`
+		Probes  map[string]ProbeType `json:"probes"`
+	} `json:"expected"`
+}
+
+// fixtureConfig is the config used to build default checkers for fixtures
+func fixtureConfig() *config.Config {
+	cfg := &config.Config{
+		ConfigCheckerNamespace:  "ava",
+		ConfigCheckerConfigName: "cluster-config",
+		KubeNodesReadyThreshold: 1,
+	}
+	cfg.Checkers = config.DefaultCheckers(cfg)
+	return cfg
+}
+
+func TestFixtures(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("testdata", "fixtures", "*.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) == 0 {
+		t.Fatal("no fixtures found")
+	}
+
+	for _, path := range paths {
+		path := path
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			data, err := ioutil.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var f fixture
+			if err := yaml.Unmarshal(data, &f); err != nil {
+				t.Fatalf("can't parse fixture. err: %s", err)
+			}
+
+			cfg := fixtureConfig()
+			if len(f.Checkers) > 0 {
+				cfg.Checkers = f.Checkers
+			}
+
+			client := newFakeClient(t, f.Objects, f.Raw)
+			runner, err := NewRunner(KubeConfig{Client: client}, cfg)
+			if err != nil {
+				t.Fatalf("can't create runner. err: %s", err)
+			}
+
+			result := runner.Run(context.Background())
+
+			if result.Status != f.Expected.Status {
+				t.Errorf("%s: expected status %s, got %s", f.Description, f.Expected.Status, result.Status)
+			}
+
+			drivers := append([]string(nil), result.Drivers...)
+			sort.Strings(drivers)
+			sort.Strings(f.Expected.Drivers)
+			if len(drivers) != 0 || len(f.Expected.Drivers) != 0 {
+				if !reflect.DeepEqual(drivers, f.Expected.Drivers) {
+					t.Errorf("%s: expected drivers %v, got %v", f.Description, f.Expected.Drivers, drivers)
+				}
+			}
+
+			probes := probeStatuses(result)
+			if !reflect.DeepEqual(probes, f.Expected.Probes) {
+				t.Errorf("%s: expected probes %v, got %v", f.Description, f.Expected.Probes, probes)
+				for _, probe := range append(result.Errors, result.Skipped...) {
+					t.Logf("%s", probe.Description)
+				}
+			}
+		})
+	}
+}
+
+// probeStatuses returns statuses of all probes in the final probe by checker name
+func probeStatuses(result *FinalProbe) map[string]ProbeType {
+	statuses := make(map[string]ProbeType)
+	all := append(append(append([]SingleFinalProbe{result.Config}, result.Oks...), result.Errors...), result.Skipped...)
+	for _, probe := range all {
+		if probe.Checker != "" {
+			statuses[probe.Checker] = probe.Status
+		}
+	}
+	return statuses
+}
@@ -0,0 +1,370 @@
+package runner
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// maxHTTPBody limits the size of response body read by the HTTP checker
+const maxHTTPBody = 1 << 20
+
+// defaultEndpointTimeout is the timeout of HTTP and TCP checkers when none is configured
+const defaultEndpointTimeout = 5 * time.Second
+
+// HTTPParams configures the HTTP checker
+type HTTPParams struct {
+	URL     string            `json:"url"`
+	Method  string            `json:"method"`
+	Headers map[string]string `json:"headers"`
+	Body    string            `json:"body"`
+	// ExpectedStatus are accepted status codes, any 2xx code by default
+	ExpectedStatus []int `json:"expectedStatus"`
+	// BodyRegex is the regular expression the response body must match
+	BodyRegex string `json:"bodyRegex"`
+	// JSONPath are assertions on the JSON response body
+	JSONPath []JSONPathAssertion `json:"jsonPath"`
+	// InsecureSkipVerify disables verification of the server certificate
+	InsecureSkipVerify bool `json:"insecureSkipVerify"`
+	// CAFile is the file with CA certificates used to verify the server certificate
+	CAFile string `json:"caFile"`
+	// ServerName overrides the name used to verify the server certificate
+	ServerName string `json:"serverName"`
+	Timeout    string `json:"timeout"`
+}
+
+// JSONPathAssertion asserts the value found under the path, i.e. {.status.phase} or $.items[0].name
+type JSONPathAssertion struct {
+	Path string `json:"path"`
+	// Equals is the expected value, when empty the path must only exist
+	Equals string `json:"equals"`
+}
+
+// HTTPResult is the result of the HTTP checker
+type HTTPResult struct {
+	URL        string `json:"url"`
+	Method     string `json:"method"`
+	StatusCode int    `json:"statusCode,omitempty"`
+	Latency    string `json:"latency"`
+	// Failures are the failed assertions
+	Failures []string `json:"failures,omitempty"`
+}
+
+// NewHTTPChecker returns a Checker that sends a request to the URL and validates the response
+func NewHTTPChecker(name string, params HTTPParams) (Checker, error) {
+	if params.URL == "" {
+		return nil, fmt.Errorf("url param is required")
+	}
+	if params.Method == "" {
+		params.Method = http.MethodGet
+	}
+
+	checker := &httpChecker{name: name, params: params}
+
+	var err error
+	if checker.timeout, err = parseTimeout(params.Timeout); err != nil {
+		return nil, err
+	}
+	if params.BodyRegex != "" {
+		if checker.bodyRegex, err = regexp.Compile(params.BodyRegex); err != nil {
+			return nil, fmt.Errorf("invalid body regex: %s", err)
+		}
+	}
+
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: params.InsecureSkipVerify,
+		ServerName:         params.ServerName,
+	}
+	if params.CAFile != "" {
+		ca, err := ioutil.ReadFile(params.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificates found in %s", params.CAFile)
+		}
+	}
+
+	checker.client = &http.Client{
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tlsConfig,
+		},
+		// redirects are reported as they are, so they can be expected
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	return checker, nil
+}
+
+// httpChecker probes an arbitrary HTTP endpoint
+type httpChecker struct {
+	name      string
+	params    HTTPParams
+	timeout   time.Duration
+	bodyRegex *regexp.Regexp
+	client    *http.Client
+}
+
+// Name returns the name of this checker
+func (r *httpChecker) Name() string { return r.name }
+
+// Check sends the request and reports the status code in the probe code
+func (r *httpChecker) Check(ctx context.Context, reporter Reporter) {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	result := HTTPResult{URL: r.params.URL, Method: r.params.Method}
+
+	req, err := http.NewRequest(r.params.Method, r.params.URL, strings.NewReader(r.params.Body))
+	if err != nil {
+		reporter.Add(NewProbeFromErr(r.Name(), "invalid request", err))
+		return
+	}
+	for key, value := range r.params.Headers {
+		if strings.EqualFold(key, "Host") {
+			req.Host = value
+			continue
+		}
+		req.Header.Set(key, value)
+	}
+
+	start := time.Now()
+	resp, err := r.client.Do(req.WithContext(ctx))
+	if err != nil {
+		result.Latency = time.Since(start).String()
+		probe := NewProbeFromErr(r.Name(), "request failed", err)
+		probe.Code = dialErrorCode(err)
+		probe.CheckerData = result
+		reporter.Add(probe)
+		return
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxHTTPBody))
+	result.Latency = time.Since(start).String()
+	result.StatusCode = resp.StatusCode
+	if err != nil {
+		result.Failures = append(result.Failures, fmt.Sprintf("can't read body: %s", err))
+	}
+
+	if !r.expectedStatus(resp.StatusCode) {
+		result.Failures = append(result.Failures, fmt.Sprintf("unexpected status code %d", resp.StatusCode))
+	}
+	if r.bodyRegex != nil && !r.bodyRegex.Match(body) {
+		result.Failures = append(result.Failures, fmt.Sprintf("body doesn't match %q", r.params.BodyRegex))
+	}
+	result.Failures = append(result.Failures, assertJSONPaths(body, r.params.JSONPath)...)
+
+	probe := &Probe{
+		Checker:     r.Name(),
+		Code:        strconv.Itoa(resp.StatusCode),
+		Status:      ProbeRunning,
+		CheckerData: result,
+	}
+	if len(result.Failures) > 0 {
+		probe.Status = ProbeFailed
+		probe.Error = strings.Join(result.Failures, ", ")
+	}
+	reporter.Add(probe)
+}
+
+// expectedStatus returns true when the status code is expected
+func (r *httpChecker) expectedStatus(code int) bool {
+	if len(r.params.ExpectedStatus) == 0 {
+		return code >= 200 && code < 300
+	}
+	for _, expected := range r.params.ExpectedStatus {
+		if code == expected {
+			return true
+		}
+	}
+	return false
+}
+
+// assertJSONPaths returns failures of assertions on the JSON body
+func assertJSONPaths(body []byte, assertions []JSONPathAssertion) []string {
+	if len(assertions) == 0 {
+		return nil
+	}
+
+	var doc interface{}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return []string{fmt.Sprintf("body is not JSON: %s", err)}
+	}
+
+	var failures []string
+	for _, assertion := range assertions {
+		value, err := lookupJSONPath(doc, assertion.Path)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %s", assertion.Path, err))
+			continue
+		}
+		if assertion.Equals != "" && fmt.Sprint(value) != assertion.Equals {
+			failures = append(failures, fmt.Sprintf("%s is %v, expected %s", assertion.Path, value, assertion.Equals))
+		}
+	}
+	return failures
+}
+
+var (
+	// jsonPathSegment matches a field with optional indexes, i.e. items[0]
+	jsonPathSegment = regexp.MustCompile(`^([^\[\]]*)((?:\[\d+\])*)$`)
+	jsonPathIndex   = regexp.MustCompile(`\d+`)
+)
+
+// lookupJSONPath returns the value under a simple JSONPath with fields and indexes,
+// both {.items[0].name} and $.items[0].name forms are supported
+func lookupJSONPath(doc interface{}, path string) (interface{}, error) {
+	path = strings.TrimSuffix(strings.TrimPrefix(path, "{"), "}")
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	if path == "" {
+		return doc, nil
+	}
+
+	current := doc
+	for _, segment := range strings.Split(path, ".") {
+		match := jsonPathSegment.FindStringSubmatch(segment)
+		if match == nil {
+			return nil, fmt.Errorf("invalid path segment %q", segment)
+		}
+
+		if match[1] != "" {
+			object, ok := current.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%s is not an object", match[1])
+			}
+			if current, ok = object[match[1]]; !ok {
+				return nil, fmt.Errorf("field %s not found", match[1])
+			}
+		}
+
+		for _, index := range jsonPathIndex.FindAllString(match[2], -1) {
+			array, ok := current.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%s is not an array", segment)
+			}
+			i, _ := strconv.Atoi(index)
+			if i >= len(array) {
+				return nil, fmt.Errorf("index %d out of range in %s", i, segment)
+			}
+			current = array[i]
+		}
+	}
+
+	return current, nil
+}
+
+// TCPParams configures the TCP checker
+type TCPParams struct {
+	// Address is the host:port to connect to
+	Address string `json:"address"`
+	Timeout string `json:"timeout"`
+}
+
+// TCPResult is the result of the TCP checker
+type TCPResult struct {
+	Address string `json:"address"`
+	Latency string `json:"latency"`
+}
+
+// NewTCPChecker returns a Checker that opens TCP connection to the address
+func NewTCPChecker(name string, params TCPParams) (Checker, error) {
+	if _, _, err := net.SplitHostPort(params.Address); err != nil {
+		return nil, fmt.Errorf("invalid address: %s", err)
+	}
+
+	timeout, err := parseTimeout(params.Timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tcpChecker{name: name, address: params.Address, timeout: timeout}, nil
+}
+
+// tcpChecker probes whether a TCP connection can be opened
+type tcpChecker struct {
+	name    string
+	address string
+	timeout time.Duration
+}
+
+// Name returns the name of this checker
+func (r *tcpChecker) Name() string { return r.name }
+
+// Check connects to the address and reports dial error in the probe code
+func (r *tcpChecker) Check(ctx context.Context, reporter Reporter) {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	start := time.Now()
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", r.address)
+	result := TCPResult{Address: r.address, Latency: time.Since(start).String()}
+	if err != nil {
+		probe := NewProbeFromErr(r.Name(), "connection failed", err)
+		probe.Code = dialErrorCode(err)
+		probe.CheckerData = result
+		reporter.Add(probe)
+		return
+	}
+	conn.Close()
+
+	reporter.Add(&Probe{
+		Checker:     r.Name(),
+		Status:      ProbeRunning,
+		CheckerData: result,
+	})
+}
+
+// dialErrorCode returns a short code describing why the connection failed
+func dialErrorCode(err error) string {
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostname x509.HostnameError
+	var invalid x509.CertificateInvalidError
+
+	switch {
+	case errors.As(err, &dnsErr):
+		return "dns_error"
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return "connection_refused"
+	case errors.Is(err, syscall.ECONNRESET):
+		return "connection_reset"
+	case errors.Is(err, syscall.EHOSTUNREACH), errors.Is(err, syscall.ENETUNREACH):
+		return "unreachable"
+	case errors.As(err, &unknownAuthority), errors.As(err, &hostname), errors.As(err, &invalid):
+		return "tls_error"
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return "timeout"
+	}
+	return "dial_error"
+}
+
+// parseTimeout parses the timeout param, empty timeout is the default one
+func parseTimeout(timeout string) (time.Duration, error) {
+	if timeout == "" {
+		return defaultEndpointTimeout, nil
+	}
+	duration, err := time.ParseDuration(timeout)
+	if err != nil {
+		return 0, fmt.Errorf("invalid timeout: %s", err)
+	}
+	return duration, nil
+}
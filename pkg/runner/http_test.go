@@ -0,0 +1,163 @@
+package runner
+
+import (
+	"context"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHTTPChecker(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"status": "ok", "items": [{"name": "metrics-server", "ready": true}]}`))
+	}))
+	defer server.Close()
+
+	headers := map[string]string{"Authorization": "Bearer token"}
+
+	tests := []struct {
+		description string
+		params      HTTPParams
+		status      ProbeType
+		code        string
+	}{
+		{
+			description: "expected status",
+			params:      HTTPParams{URL: server.URL, Headers: headers},
+			status:      ProbeRunning,
+			code:        "200",
+		},
+		{
+			description: "unexpected status",
+			params:      HTTPParams{URL: server.URL},
+			status:      ProbeFailed,
+			code:        "401",
+		},
+		{
+			description: "configured expected status",
+			params:      HTTPParams{URL: server.URL, ExpectedStatus: []int{401}},
+			status:      ProbeRunning,
+			code:        "401",
+		},
+		{
+			description: "body regex",
+			params:      HTTPParams{URL: server.URL, Headers: headers, BodyRegex: `"status":\s*"failed"`},
+			status:      ProbeFailed,
+			code:        "200",
+		},
+		{
+			description: "json path assertions",
+			params: HTTPParams{URL: server.URL, Headers: headers, JSONPath: []JSONPathAssertion{
+				{Path: "$.status", Equals: "ok"},
+				{Path: "{.items[0].ready}", Equals: "true"},
+				{Path: "items[0].name"},
+			}},
+			status: ProbeRunning,
+			code:   "200",
+		},
+		{
+			description: "failed json path assertion",
+			params: HTTPParams{URL: server.URL, Headers: headers, JSONPath: []JSONPathAssertion{
+				{Path: "$.items[1].name"},
+			}},
+			status: ProbeFailed,
+			code:   "200",
+		},
+		{
+			description: "connection refused",
+			params:      HTTPParams{URL: "http://" + closedAddress(t)},
+			status:      ProbeFailed,
+			code:        "connection_refused",
+		},
+	}
+
+	for _, test := range tests {
+		checker, err := NewHTTPChecker("http", test.params)
+		if err != nil {
+			t.Fatalf("%s: %s", test.description, err)
+		}
+
+		var probes Probes
+		checker.Check(context.Background(), &probes)
+		probe := probes.GetProbes()[0]
+		if probe.Status != test.status || probe.Code != test.code {
+			t.Errorf("%s: expected %s with code %s, got %s with code %s: %s",
+				test.description, test.status, test.code, probe.Status, probe.Code, probe.Error)
+		}
+	}
+}
+
+func TestHTTPCheckerTLSVerification(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	// the verifying request rejects the certificate during the handshake
+	server.Config.ErrorLog = log.New(ioutil.Discard, "", 0)
+	server.StartTLS()
+	defer server.Close()
+
+	for _, insecure := range []bool{false, true} {
+		checker, err := NewHTTPChecker("https", HTTPParams{URL: server.URL, InsecureSkipVerify: insecure})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var probes Probes
+		checker.Check(context.Background(), &probes)
+		probe := probes.GetProbes()[0]
+		if insecure && probe.Status != ProbeRunning {
+			t.Errorf("expected insecure request to succeed, got %s", probe.Error)
+		}
+		if !insecure && probe.Code != "tls_error" {
+			t.Errorf("expected tls_error code, got %s: %s", probe.Code, probe.Error)
+		}
+	}
+}
+
+func TestTCPChecker(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	tests := []struct {
+		address string
+		status  ProbeType
+		code    string
+	}{
+		{address: listener.Addr().String(), status: ProbeRunning},
+		{address: closedAddress(t), status: ProbeFailed, code: "connection_refused"},
+	}
+
+	for _, test := range tests {
+		checker, err := NewTCPChecker("tcp", TCPParams{Address: test.address, Timeout: "1s"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var probes Probes
+		checker.Check(context.Background(), &probes)
+		probe := probes.GetProbes()[0]
+		if probe.Status != test.status || probe.Code != test.code {
+			t.Errorf("%s: expected %s with code %q, got %s with code %q", test.address, test.status, test.code, probe.Status, probe.Code)
+		}
+	}
+}
+
+// closedAddress returns a local address nothing listens on
+func closedAddress(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := listener.Addr().String()
+	listener.Close()
+	return address
+}
@@ -0,0 +1,239 @@
+package runner
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+)
+
+// jsonSchema is a JSON Schema supporting the subset of keywords used to validate configuration:
+// type, enum, const, required, properties, additionalProperties, items, pattern,
+// minLength, maxLength, minimum, maximum, minItems and maxItems
+type jsonSchema map[string]interface{}
+
+// schemaKeywords are supported keywords, annotations are allowed as they don't affect validation
+var schemaKeywords = map[string]bool{
+	"type": true, "enum": true, "const": true, "required": true, "properties": true,
+	"additionalProperties": true, "items": true, "pattern": true, "minLength": true, "maxLength": true,
+	"minimum": true, "maximum": true, "minItems": true, "maxItems": true,
+	"$schema": true, "$id": true, "$comment": true, "title": true, "description": true, "default": true, "examples": true,
+}
+
+// newJSONSchema validates the schema and returns it
+func newJSONSchema(schema map[string]interface{}) (jsonSchema, error) {
+	if err := checkSchema(schema, "$"); err != nil {
+		return nil, err
+	}
+	return jsonSchema(schema), nil
+}
+
+// checkSchema validates the keywords of the schema and its subschemas, unsupported keywords are rejected
+// so the schema never silently accepts values it's meant to reject
+func checkSchema(schema map[string]interface{}, path string) error {
+	keywords := make([]string, 0, len(schema))
+	for keyword := range schema {
+		keywords = append(keywords, keyword)
+	}
+	sort.Strings(keywords)
+	for _, keyword := range keywords {
+		if !schemaKeywords[keyword] {
+			return fmt.Errorf("%s: unsupported keyword %s", path, keyword)
+		}
+		if err := checkKeywordValue(keyword, schema[keyword]); err != nil {
+			return fmt.Errorf("%s: %s", path, err)
+		}
+	}
+
+	if pattern, ok := schema["pattern"].(string); ok {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("%s: invalid pattern: %s", path, err)
+		}
+	}
+	if properties, ok := schema["properties"].(map[string]interface{}); ok {
+		for name, property := range properties {
+			subschema, ok := property.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("%s.%s: schema must be an object", path, name)
+			}
+			if err := checkSchema(subschema, path+"."+name); err != nil {
+				return err
+			}
+		}
+	}
+	if items, ok := schema["items"].(map[string]interface{}); ok {
+		return checkSchema(items, path+"[]")
+	}
+	return nil
+}
+
+// checkKeywordValue validates the type of the keyword value, validation skips keywords with values of other types
+func checkKeywordValue(keyword string, value interface{}) error {
+	switch keyword {
+	case "minLength", "maxLength", "minimum", "maximum", "minItems", "maxItems":
+		if _, ok := value.(float64); !ok {
+			return fmt.Errorf("%s must be a number", keyword)
+		}
+	case "required":
+		names, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("required must be an array")
+		}
+		for _, name := range names {
+			if _, ok := name.(string); !ok {
+				return fmt.Errorf("required must be an array of strings")
+			}
+		}
+	case "enum":
+		if _, ok := value.([]interface{}); !ok {
+			return fmt.Errorf("enum must be an array")
+		}
+	case "properties":
+		if _, ok := value.(map[string]interface{}); !ok {
+			return fmt.Errorf("properties must be an object")
+		}
+	case "pattern":
+		if _, ok := value.(string); !ok {
+			return fmt.Errorf("pattern must be a string")
+		}
+	case "additionalProperties":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("additionalProperties must be a boolean")
+		}
+	case "items":
+		if _, ok := value.(map[string]interface{}); !ok {
+			return fmt.Errorf("items must be a schema object")
+		}
+	}
+	return nil
+}
+
+// validate returns problems of the decoded JSON value, numbers must be float64
+func (s jsonSchema) validate(value interface{}) []string {
+	return validateSchema(s, value, "$")
+}
+
+func validateSchema(schema map[string]interface{}, value interface{}, path string) []string {
+	var problems []string
+	fail := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf("%s: %s", path, fmt.Sprintf(format, args...)))
+	}
+
+	if expected, ok := schema["type"]; ok && !matchesType(expected, value) {
+		fail("expected type %v, got %s", expected, jsonType(value))
+		return problems
+	}
+	if expected, ok := schema["const"]; ok && !reflect.DeepEqual(expected, value) {
+		fail("expected %v, got %v", expected, value)
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, allowed := range enum {
+			found = found || reflect.DeepEqual(allowed, value)
+		}
+		if !found {
+			fail("%v is not one of %v", value, enum)
+		}
+	}
+
+	switch v := value.(type) {
+	case string:
+		if pattern, ok := schema["pattern"].(string); ok && !regexp.MustCompile(pattern).MatchString(v) {
+			fail("%q doesn't match %q", v, pattern)
+		}
+		if min, ok := schema["minLength"].(float64); ok && float64(len(v)) < min {
+			fail("length %d is less than %v", len(v), min)
+		}
+		if max, ok := schema["maxLength"].(float64); ok && float64(len(v)) > max {
+			fail("length %d is greater than %v", len(v), max)
+		}
+	case float64:
+		if min, ok := schema["minimum"].(float64); ok && v < min {
+			fail("%v is less than %v", v, min)
+		}
+		if max, ok := schema["maximum"].(float64); ok && v > max {
+			fail("%v is greater than %v", v, max)
+		}
+	case []interface{}:
+		if min, ok := schema["minItems"].(float64); ok && float64(len(v)) < min {
+			fail("%d items is less than %v", len(v), min)
+		}
+		if max, ok := schema["maxItems"].(float64); ok && float64(len(v)) > max {
+			fail("%d items is more than %v", len(v), max)
+		}
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range v {
+				problems = append(problems, validateSchema(items, item, fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+	case map[string]interface{}:
+		if required, ok := schema["required"].([]interface{}); ok {
+			for _, name := range required {
+				if _, ok := v[fmt.Sprint(name)]; !ok {
+					fail("missing required property %v", name)
+				}
+			}
+		}
+
+		properties, _ := schema["properties"].(map[string]interface{})
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			property, ok := properties[name].(map[string]interface{})
+			if !ok {
+				if additional, ok := schema["additionalProperties"].(bool); ok && !additional {
+					fail("unexpected property %s", name)
+				}
+				continue
+			}
+			problems = append(problems, validateSchema(property, v[name], path+"."+name)...)
+		}
+	}
+
+	return problems
+}
+
+// matchesType returns true when the value has the expected type or one of the expected types
+func matchesType(expected interface{}, value interface{}) bool {
+	types, ok := expected.([]interface{})
+	if !ok {
+		types = []interface{}{expected}
+	}
+
+	actual := jsonType(value)
+	for _, t := range types {
+		switch {
+		case t == actual:
+			return true
+		case t == "number" && actual == "integer":
+			return true
+		}
+	}
+	return false
+}
+
+// jsonType returns the JSON Schema type of the decoded JSON value
+func jsonType(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
@@ -8,12 +8,15 @@ import (
 	"io/ioutil"
 
 	kube "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 )
 
 // KubeConfig defines Kubernetes access configuration
 type KubeConfig struct {
 	// Client is the initialized Kubernetes client
-	Client *kube.Clientset
+	Client kube.Interface
+	// RestConfig is the config the client was created from, nil when unknown
+	RestConfig *rest.Config
 }
 
 // kubeHealthz is httpResponseChecker that interprets health status of common kubernetes services.
@@ -29,14 +32,15 @@ func kubeHealthz(response io.Reader) error {
 }
 
 // KubeStatusChecker is a function that can check status of kubernetes services.
-type KubeStatusChecker func(ctx context.Context, client *kube.Clientset) (interface{}, error)
+// Data returned together with an error is reported with the failed probe.
+type KubeStatusChecker func(ctx context.Context, client kube.Interface) (interface{}, error)
 
 // KubeChecker implements Checker that can check and report problems
 // with kubernetes services.
 type KubeChecker struct {
 	name    string
 	checker KubeStatusChecker
-	client  *kube.Clientset
+	client  kube.Interface
 }
 
 // Name returns the name of this checker
@@ -47,7 +51,9 @@ func (r *KubeChecker) Name() string { return r.name }
 func (r *KubeChecker) Check(ctx context.Context, reporter Reporter) {
 	res, err := r.checker(ctx, r.client)
 	if err != nil {
-		reporter.Add(NewProbeFromErr(r.name, noErrorDetail, err))
+		probe := NewProbeFromErr(r.name, noErrorDetail, err)
+		probe.CheckerData = res
+		reporter.Add(probe)
 		return
 	}
 	reporter.Add(&Probe{
@@ -0,0 +1,54 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/mateuszdyminski/k8s-status/pkg/config"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	// register the oidc auth provider used by kubeconfig users
+	_ "k8s.io/client-go/plugin/pkg/client/auth/oidc"
+)
+
+// outOfCluster returns true when any of kubeconfig options is set
+func outOfCluster(cfg *config.Config) bool {
+	return cfg.KubeConfigPath != "" || cfg.KubeContext != "" || cfg.KubeImpersonate != ""
+}
+
+// restConfig creates the rest config from kubeconfig when any of kubeconfig options is set
+// and falls back to the in-cluster config otherwise. Both the path and KUBECONFIG can be
+// lists of files separated like PATH.
+func restConfig(cfg *config.Config) (*rest.Config, error) {
+	if !outOfCluster(cfg) {
+		return rest.InClusterConfig()
+	}
+
+	path := cfg.KubeConfigPath
+	if path == "" {
+		path = os.Getenv("KUBECONFIG")
+	}
+	if path == "" {
+		path = filepath.Join(os.Getenv("HOME"), ".kube", "config")
+	}
+
+	restCfg, err := loadKubeconfig(filepath.SplitList(path), cfg.KubeContext, cfg.KubeImpersonate)
+	if err != nil {
+		return nil, fmt.Errorf("can't load kubeconfig %s. err: %s", path, err)
+	}
+	return restCfg, nil
+}
+
+// loadKubeconfig builds the rest config from kubeconfig files merged like kubectl does,
+// the first file which sets a value wins. The current context is used when contextName is empty.
+func loadKubeconfig(paths []string, contextName, impersonate string) (*rest.Config, error) {
+	rules := &clientcmd.ClientConfigLoadingRules{Precedence: paths}
+	overrides := &clientcmd.ConfigOverrides{
+		CurrentContext: contextName,
+		AuthInfo:       clientcmdapi.AuthInfo{Impersonate: impersonate},
+	}
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides).ClientConfig()
+}
@@ -0,0 +1,166 @@
+package runner
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeKubeconfigFiles writes files to a temporary directory and returns it with its cleanup function
+func writeKubeconfigFiles(t *testing.T, files map[string]string) (string, func()) {
+	dir, err := ioutil.TempDir("", "kubeconfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+func TestLoadKubeconfig(t *testing.T) {
+	dir, cleanup := writeKubeconfigFiles(t, map[string]string{
+		"config": `
+current-context: dev
+clusters:
+- name: dev
+  cluster: {server: "https://dev:6443", certificate-authority: certs/ca.crt}
+contexts:
+- name: dev
+  context: {cluster: dev, user: dev-token}
+- name: prod
+  context: {cluster: prod, user: prod-exec}
+- name: missing-cluster
+  context: {cluster: staging, user: dev-token}
+users:
+- name: dev-token
+  user: {tokenFile: token}
+`,
+		"certs/ca.crt": "ca",
+		"token":        "secret-token\n",
+		"prod": `
+current-context: prod
+clusters:
+- name: prod
+  cluster: {server: "https://prod:6443", insecure-skip-tls-verify: true}
+- name: dev
+  cluster: {server: "https://ignored:6443"}
+users:
+- name: prod-exec
+  user:
+    exec: {apiVersion: client.authentication.k8s.io/v1beta1, command: aws-iam-authenticator}
+`,
+	})
+	defer cleanup()
+	path := func(name string) string { return filepath.Join(dir, name) }
+
+	tests := []struct {
+		name    string
+		paths   []string
+		context string
+		host    string
+		err     string
+	}{
+		{name: "current context", paths: []string{path("config")}, host: "https://dev:6443"},
+		{name: "explicit context", paths: []string{path("config"), path("prod")}, context: "prod", host: "https://prod:6443"},
+		{name: "first current context wins", paths: []string{path("prod"), path("config")}, host: "https://prod:6443"},
+		{name: "first cluster wins", paths: []string{path("prod"), path("config")}, context: "dev", host: "https://ignored:6443"},
+		{name: "missing file is skipped", paths: []string{path("missing"), path("config")}, host: "https://dev:6443"},
+		{name: "no file exists", paths: []string{path("missing")}, err: "no configuration has been provided"},
+		{name: "unknown context", paths: []string{path("config")}, context: "qa", err: `context "qa" does not exist`},
+		{name: "missing cluster", paths: []string{path("config")}, context: "missing-cluster", err: "invalid configuration"},
+		{name: "cluster defined in another file", paths: []string{path("config")}, context: "prod", err: "invalid configuration"},
+	}
+
+	for _, test := range tests {
+		restCfg, err := loadKubeconfig(test.paths, test.context, "")
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("%s: expected error %q, got %v", test.name, test.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", test.name, err)
+			continue
+		}
+		if restCfg.Host != test.host {
+			t.Errorf("%s: expected host %s, got %s", test.name, test.host, restCfg.Host)
+		}
+	}
+}
+
+func TestLoadKubeconfigCredentials(t *testing.T) {
+	dir, cleanup := writeKubeconfigFiles(t, map[string]string{
+		"config": `
+current-context: dev
+clusters:
+- name: dev
+  cluster: {server: "https://dev:6443", certificate-authority: certs/ca.crt}
+- name: prod
+  cluster: {server: "https://prod:6443"}
+contexts:
+- name: dev
+  context: {cluster: dev, user: dev}
+- name: prod
+  context: {cluster: prod, user: prod}
+- name: oidc
+  context: {cluster: prod, user: oidc}
+users:
+- name: dev
+  user: {tokenFile: token, client-certificate: certs/client.crt, client-key: certs/client.key}
+- name: prod
+  user:
+    exec: {apiVersion: client.authentication.k8s.io/v1beta1, command: bin/aws-iam-authenticator}
+- name: oidc
+  user:
+    auth-provider:
+      name: oidc
+      config: {idp-issuer-url: "https://accounts.example.com", client-id: k8s-status, id-token: token}
+`,
+		"certs/ca.crt":     "ca",
+		"certs/client.crt": "crt",
+		"certs/client.key": "key",
+		"token":            "secret-token",
+	})
+	defer cleanup()
+	path := filepath.Join(dir, "config")
+
+	dev, err := loadKubeconfig([]string{path}, "", "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dev.BearerToken != "secret-token" {
+		t.Errorf("expected token read from the relative token file, got %q", dev.BearerToken)
+	}
+	if dev.CAFile != filepath.Join(dir, "certs", "ca.crt") || dev.CertFile != filepath.Join(dir, "certs", "client.crt") {
+		t.Errorf("expected relative paths resolved against the kubeconfig directory, got %s and %s", dev.CAFile, dev.CertFile)
+	}
+	if dev.Impersonate.UserName != "admin" {
+		t.Errorf("expected impersonated user admin, got %q", dev.Impersonate.UserName)
+	}
+
+	prod, err := loadKubeconfig([]string{path}, "prod", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prod.ExecProvider == nil || prod.ExecProvider.Command != filepath.Join(dir, "bin", "aws-iam-authenticator") {
+		t.Errorf("expected exec credentials with the command resolved against the kubeconfig directory, got %+v", prod.ExecProvider)
+	}
+
+	oidc, err := loadKubeconfig([]string{path}, "oidc", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if oidc.AuthProvider == nil || oidc.AuthProvider.Name != "oidc" {
+		t.Errorf("expected oidc auth provider, got %+v", oidc.AuthProvider)
+	}
+}
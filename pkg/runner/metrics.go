@@ -0,0 +1,143 @@
+package runner
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics exports results of checker runs to Prometheus
+type Metrics struct {
+	// ProbeStatus is 1 for checkers whose probes passed and 0 for checkers with failed probes.
+	// It's labeled by the top-level checker and the worst severity of its failed probes, so probes
+	// of single objects don't add label values. Checkers with only skipped probes are not exported.
+	ProbeStatus *prometheus.GaugeVec
+	// ClusterStatus is 1 for the current cluster status and 0 for the other ones
+	ClusterStatus *prometheus.GaugeVec
+	// CheckerDuration is the time spent running a checker, not including waiting for a free slot
+	CheckerDuration *prometheus.HistogramVec
+	// CheckerErrors counts failed probes by the probe code, i.e. HTTP status or dial error
+	CheckerErrors *prometheus.CounterVec
+
+	mu sync.Mutex
+	// probeLabels are label values of probe statuses exported by the last run
+	probeLabels map[[2]string]bool
+}
+
+// NewMetrics creates checker metrics and registers them in the default Prometheus registry
+func NewMetrics() *Metrics {
+	metrics := newMetrics()
+
+	prometheus.MustRegister(metrics.ProbeStatus)
+	prometheus.MustRegister(metrics.ClusterStatus)
+	prometheus.MustRegister(metrics.CheckerDuration)
+	prometheus.MustRegister(metrics.CheckerErrors)
+
+	return metrics
+}
+
+// newMetrics creates checker metrics without registering them
+func newMetrics() *Metrics {
+	return &Metrics{
+		ProbeStatus: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "k8status",
+			Name:      "probe_status",
+			Help:      "Status of the checker probes from the last run, 1 when all passed and 0 when any failed.",
+		}, []string{"checker", "severity"}),
+		ClusterStatus: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "k8status",
+			Name:      "cluster_status",
+			Help:      "Cluster status from the last run, 1 for the current status.",
+		}, []string{"status"}),
+		CheckerDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "k8status",
+			Name:      "checker_duration_seconds",
+			Help:      "Seconds spent running the checker.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"checker"}),
+		CheckerErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "k8status",
+			Name:      "checker_errors_total",
+			Help:      "The total number of failed probes by the checker and the reason.",
+		}, []string{"checker", "reason"}),
+		probeLabels: make(map[[2]string]bool),
+	}
+}
+
+// observeChecker records the duration and failed probes of a single checker.
+// The duration is zero and not recorded when the checker didn't get to run.
+func (m *Metrics) observeChecker(checker string, duration time.Duration, probes []*Probe) {
+	if m == nil {
+		return
+	}
+
+	if duration > 0 {
+		m.CheckerDuration.WithLabelValues(checker).Observe(duration.Seconds())
+	}
+	for _, probe := range probes {
+		if probe.Status != ProbeFailed {
+			continue
+		}
+		reason := probe.Code
+		if reason == "" {
+			reason = "failed"
+		}
+		m.CheckerErrors.WithLabelValues(checker, reason).Inc()
+	}
+}
+
+// observeRun records statuses of probes aggregated by top-level checkers and the cluster status of the run.
+// Statuses of checkers which are not reported anymore are removed.
+func (m *Metrics) observeRun(result *FinalProbe, probes []*Probe) {
+	if m == nil {
+		return
+	}
+
+	// the worst severity of failed probes by checkers, none when all probes passed
+	severities := make(map[string]ProbeSeverity)
+	for _, probe := range probes {
+		checker := probe.source
+		if checker == "" {
+			checker = probe.Checker
+		}
+
+		switch probe.Status {
+		case ProbeRunning:
+			if _, ok := severities[checker]; !ok {
+				severities[checker] = ProbeNone
+			}
+		case ProbeFailed:
+			if severity := failureSeverity(probe); severityRank[severity] >= severityRank[severities[checker]] {
+				severities[checker] = severity
+			}
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	labels := make(map[[2]string]bool)
+	for checker, severity := range severities {
+		value := 0.0
+		if severity == ProbeNone {
+			value = 1
+		}
+		labels[[2]string{checker, string(severity)}] = true
+		m.ProbeStatus.WithLabelValues(checker, string(severity)).Set(value)
+	}
+	for label := range m.probeLabels {
+		if !labels[label] {
+			m.ProbeStatus.DeleteLabelValues(label[0], label[1])
+		}
+	}
+	m.probeLabels = labels
+
+	for _, status := range []ClusterStatusType{ClusterHealthy, ClusterDegraded, ClusterFailed} {
+		value := 0.0
+		if result.Status == status {
+			value = 1
+		}
+		m.ClusterStatus.WithLabelValues(string(status)).Set(value)
+	}
+}
@@ -0,0 +1,144 @@
+package runner
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// gather returns values of metrics by the metric name and label values
+func gather(t *testing.T, registry *prometheus.Registry) map[string]float64 {
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := make(map[string]float64)
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			var labels []string
+			for _, label := range metric.GetLabel() {
+				labels = append(labels, label.GetValue())
+			}
+			key := family.GetName() + "{" + strings.Join(labels, ",") + "}"
+
+			switch {
+			case metric.Gauge != nil:
+				values[key] = metric.GetGauge().GetValue()
+			case metric.Counter != nil:
+				values[key] = metric.GetCounter().GetValue()
+			case metric.Histogram != nil:
+				values[key] = float64(metric.GetHistogram().GetSampleCount())
+			}
+		}
+	}
+	return values
+}
+
+func TestRunUpdatesMetrics(t *testing.T) {
+	failing := &stubChecker{name: "failing", status: ProbeFailed}
+	runner := newStubRunner(
+		failing,
+		&stubChecker{name: "healthy", status: ProbeRunning},
+		&stubChecker{name: "slow", status: ProbeRunning, delay: time.Second},
+	)
+
+	metrics := newMetrics()
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(metrics.ProbeStatus, metrics.ClusterStatus, metrics.CheckerDuration, metrics.CheckerErrors)
+	runner.SetMetrics(metrics)
+
+	runner.Run(context.Background())
+	values := gather(t, registry)
+
+	expected := map[string]float64{
+		"k8status_probe_status{failing,critical}":       0,
+		"k8status_probe_status{healthy,none}":           1,
+		"k8status_probe_status{slow,critical}":          0,
+		"k8status_cluster_status{failed}":               1,
+		"k8status_cluster_status{degraded}":             0,
+		"k8status_cluster_status{healthy}":              0,
+		"k8status_checker_duration_seconds{healthy}":    1,
+		"k8status_checker_errors_total{failing,failed}": 1,
+		"k8status_checker_errors_total{slow,timeout}":   1,
+		"k8status_checker_duration_seconds{failing}":    1,
+		"k8status_checker_duration_seconds{slow}":       1,
+	}
+	for key, value := range expected {
+		if actual, ok := values[key]; !ok || actual != value {
+			t.Errorf("expected %s = %v, got %v (exported: %v)", key, value, actual, ok)
+		}
+	}
+
+	// probe statuses not reported anymore are removed
+	failing.status = ProbeRunning
+	runner.Run(context.Background())
+	values = gather(t, registry)
+
+	var statuses []string
+	for key := range values {
+		if strings.HasPrefix(key, "k8status_probe_status{failing") {
+			statuses = append(statuses, key)
+		}
+	}
+	sort.Strings(statuses)
+	if strings.Join(statuses, " ") != "k8status_probe_status{failing,none}" {
+		t.Errorf("expected only passed status of failing checker, got %v", statuses)
+	}
+	if values["k8status_checker_errors_total{failing,failed}"] != 1 {
+		t.Errorf("expected errors counter to stay at 1, got %v", values["k8status_checker_errors_total{failing,failed}"])
+	}
+}
+
+func TestRunDoesNotRecordWaitingForSlotInDuration(t *testing.T) {
+	runner := newStubRunner(
+		&stubChecker{name: "first", status: ProbeRunning, delay: 200 * time.Millisecond},
+		&stubChecker{name: "second", status: ProbeRunning, delay: 200 * time.Millisecond},
+	)
+	runner.concurrency = 1
+	runner.timeout = time.Second
+
+	metrics := newMetrics()
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(metrics.CheckerDuration)
+	runner.SetMetrics(metrics)
+
+	runner.Run(context.Background())
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			// one of the checkers waits for the other one, which would double its duration
+			if seconds := metric.GetHistogram().GetSampleSum(); seconds > 0.35 {
+				t.Errorf("expected duration of %v without waiting for a slot, got %vs", metric.GetLabel(), seconds)
+			}
+		}
+	}
+}
+
+func TestRunAggregatesProbeStatusByChecker(t *testing.T) {
+	runner := newStubRunner(&checkerGroup{name: "pods", Checkers: Checkers{
+		&stubChecker{name: "pods/shop/api-0", status: ProbeFailed},
+		&stubChecker{name: "pods/shop/web-0", status: ProbeRunning},
+		&stubChecker{name: "pods/shop/web-1", status: ProbeFailed},
+	}})
+
+	metrics := newMetrics()
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(metrics.ProbeStatus)
+	runner.SetMetrics(metrics)
+
+	runner.Run(context.Background())
+
+	values := gather(t, registry)
+	if status, ok := values["k8status_probe_status{pods,critical}"]; len(values) != 1 || !ok || status != 0 {
+		t.Errorf("expected single failed status of pods checker, got %v", values)
+	}
+}
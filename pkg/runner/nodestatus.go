@@ -3,6 +3,8 @@ package runner
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strings"
 
 	"k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -11,23 +13,108 @@ import (
 	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 )
 
+// NodeRule defines how many nodes with a problem fail the nodes status checker
+type NodeRule struct {
+	// Threshold is the number of affected nodes which fails the checker, 0 disables the rule
+	Threshold int `json:"threshold"`
+	// Severity is the severity of the probe when the rule fails, warning by default
+	Severity ProbeSeverity `json:"severity"`
+}
+
+// NodesRules defines when the nodes status checker fails
+type NodesRules struct {
+	// ReadyThreshold is the minimal number of ready nodes, failing it is critical
+	ReadyThreshold int `json:"readyThreshold"`
+	// Conditions are rules for node conditions which denote a problem when they are true
+	Conditions map[v1.NodeConditionType]NodeRule `json:"conditions"`
+	// Unschedulable is the rule for cordoned nodes
+	Unschedulable NodeRule `json:"unschedulable"`
+	// NoExecuteTaints is the rule for nodes with NoExecute taints
+	NoExecuteTaints NodeRule `json:"noExecuteTaints"`
+}
+
+// DefaultNodesRules returns rules which warn about any node with a problem
+func DefaultNodesRules(readyThreshold int) NodesRules {
+	warnOnAny := NodeRule{Threshold: 1, Severity: ProbeWarning}
+	return NodesRules{
+		ReadyThreshold: readyThreshold,
+		Conditions: map[v1.NodeConditionType]NodeRule{
+			v1.NodeMemoryPressure:     warnOnAny,
+			v1.NodeDiskPressure:       warnOnAny,
+			v1.NodePIDPressure:        warnOnAny,
+			v1.NodeNetworkUnavailable: warnOnAny,
+		},
+		Unschedulable:   warnOnAny,
+		NoExecuteTaints: warnOnAny,
+	}
+}
+
+// validate fails when any rule has an unknown severity
+func (r NodesRules) validate() error {
+	for _, conditionType := range sortedConditionTypes(r.Conditions) {
+		if err := r.Conditions[conditionType].validate(string(conditionType)); err != nil {
+			return err
+		}
+	}
+	if err := r.Unschedulable.validate("unschedulable"); err != nil {
+		return err
+	}
+	return r.NoExecuteTaints.validate("noExecuteTaints")
+}
+
+// validate fails when the severity of the rule is unknown
+func (r NodeRule) validate(name string) error {
+	switch r.Severity {
+	case "", ProbeWarning, ProbeCritical:
+		return nil
+	default:
+		return fmt.Errorf("unknown severity %q of rule %s", r.Severity, name)
+	}
+}
+
+// NodeSummary is the summary of a single node reported by the nodes status checker
+type NodeSummary struct {
+	// Name is the name of the node
+	Name string `json:"name"`
+	// Ready is true when the node is ready
+	Ready bool `json:"ready"`
+	// Unschedulable is true when the node is cordoned
+	Unschedulable bool `json:"unschedulable"`
+	// Conditions are the problem conditions of the node, i.e. MemoryPressure
+	Conditions []string `json:"conditions,omitempty"`
+	// NoExecuteTaints are keys of NoExecute taints of the node
+	NoExecuteTaints []string `json:"noExecuteTaints,omitempty"`
+}
+
+// NodesSummary is the data reported by the nodes status checker
+type NodesSummary struct {
+	// Total is the number of nodes in the cluster
+	Total int `json:"total"`
+	// Ready is the number of ready nodes
+	Ready int `json:"ready"`
+	// Nodes are summaries of all nodes
+	Nodes []NodeSummary `json:"nodes"`
+}
+
 // NewNodesStatusChecker returns a Checker that tests kubernetes nodes availability
-func NewNodesStatusChecker(config KubeConfig, nodesReadyThreshold int) Checker {
+func NewNodesStatusChecker(config KubeConfig, name string, rules NodesRules) Checker {
 	return &nodesStatusChecker{
-		client:              config.Client.CoreV1(),
-		nodesReadyThreshold: nodesReadyThreshold,
+		name:   name,
+		client: config.Client.CoreV1(),
+		rules:  rules,
 	}
 }
 
 // nodesStatusChecker tests and reports health failures in kubernetes
 // nodes availability
 type nodesStatusChecker struct {
-	client              corev1.CoreV1Interface
-	nodesReadyThreshold int
+	name   string
+	client corev1.CoreV1Interface
+	rules  NodesRules
 }
 
 // Name returns the name of this checker
-func (r *nodesStatusChecker) Name() string { return NodesStatusCheckerID }
+func (r *nodesStatusChecker) Name() string { return r.name }
 
 // Check validates the status of kubernetes components
 func (r *nodesStatusChecker) Check(ctx context.Context, reporter Reporter) {
@@ -41,38 +128,103 @@ func (r *nodesStatusChecker) Check(ctx context.Context, reporter Reporter) {
 		reporter.Add(NewProbeFromErr(r.Name(), reason, err))
 		return
 	}
-	var nodesReady int
-	conditions := make([]interface{}, 0, 0)
+
+	summary := NodesSummary{Total: len(statuses.Items), Nodes: make([]NodeSummary, 0, len(statuses.Items))}
+	affected := make(map[string]int)
 	for _, item := range statuses.Items {
-		for _, condition := range item.Status.Conditions {
-			conditions = append(conditions, condition)
-			if condition.Type != v1.NodeReady {
-				continue
-			}
-			if condition.Status == v1.ConditionTrue {
-				nodesReady++
-				continue
-			}
+		node := summarizeNode(item, r.rules)
+		if node.Ready {
+			summary.Ready++
+		}
+		if node.Unschedulable {
+			affected["unschedulable"]++
 		}
+		if len(node.NoExecuteTaints) > 0 {
+			affected["NoExecute taints"]++
+		}
+		for _, condition := range node.Conditions {
+			affected[condition]++
+		}
+		summary.Nodes = append(summary.Nodes, node)
 	}
 
-	if nodesReady < r.nodesReadyThreshold {
+	// the probe gets the worst severity of all failed rules
+	var failures []string
+	severity := ProbeWarning
+	fail := func(rule NodeRule, msg string) {
+		failures = append(failures, msg)
+		if rule.Severity == ProbeCritical {
+			severity = ProbeCritical
+		}
+	}
+
+	if summary.Ready < r.rules.ReadyThreshold {
+		fail(NodeRule{Severity: ProbeCritical}, fmt.Sprintf("Not enough ready nodes: %v (threshold %v)",
+			summary.Ready, r.rules.ReadyThreshold))
+	}
+	check := func(rule NodeRule, problem string) {
+		if rule.Threshold > 0 && affected[problem] >= rule.Threshold {
+			fail(rule, fmt.Sprintf("Nodes with %s: %v (threshold %v)", problem, affected[problem], rule.Threshold))
+		}
+	}
+	for _, conditionType := range sortedConditionTypes(r.rules.Conditions) {
+		check(r.rules.Conditions[conditionType], string(conditionType))
+	}
+	check(r.rules.Unschedulable, "unschedulable")
+	check(r.rules.NoExecuteTaints, "NoExecute taints")
+
+	if len(failures) > 0 {
 		reporter.Add(&Probe{
-			Checker: r.Name(),
-			Status:  ProbeFailed,
-			Error: fmt.Sprintf("Not enough ready nodes: %v (threshold %v)",
-				nodesReady, r.nodesReadyThreshold),
-			CheckerData: conditions,
+			Checker:     r.Name(),
+			Status:      ProbeFailed,
+			Severity:    severity,
+			Error:       strings.Join(failures, ", "),
+			CheckerData: summary,
 		})
 	} else {
 		reporter.Add(&Probe{
 			Checker:     r.Name(),
 			Status:      ProbeRunning,
-			CheckerData: conditions,
+			CheckerData: summary,
 		})
 	}
 }
 
+// summarizeNode returns the summary of the node with conditions which are problems according to rules
+func summarizeNode(node v1.Node, rules NodesRules) NodeSummary {
+	summary := NodeSummary{
+		Name:          node.Name,
+		Unschedulable: node.Spec.Unschedulable,
+	}
+
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == v1.NodeReady {
+			summary.Ready = condition.Status == v1.ConditionTrue
+			continue
+		}
+		if _, ok := rules.Conditions[condition.Type]; ok && condition.Status == v1.ConditionTrue {
+			summary.Conditions = append(summary.Conditions, string(condition.Type))
+		}
+	}
+
+	for _, taint := range node.Spec.Taints {
+		if taint.Effect == v1.TaintEffectNoExecute {
+			summary.NoExecuteTaints = append(summary.NoExecuteTaints, taint.Key)
+		}
+	}
+
+	return summary
+}
+
+func sortedConditionTypes(conditions map[v1.NodeConditionType]NodeRule) []v1.NodeConditionType {
+	types := make([]v1.NodeConditionType, 0, len(conditions))
+	for conditionType := range conditions {
+		types = append(types, conditionType)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	return types
+}
+
 // NewNodeStatusChecker returns a Checker that validates availability
 // of a single kubernetes node
 func NewNodeStatusChecker(config KubeConfig, nodeName string) Checker {
@@ -91,7 +243,17 @@ type nodeStatusChecker struct {
 }
 
 // Name returns the name of this checker
-func (r *nodeStatusChecker) Name() string { return NodeStatusCheckerID }
+func (r *nodeStatusChecker) Name() string {
+	return fmt.Sprintf("%s/%s", NodeStatusCheckerID, r.nodeName)
+}
+
+// NodeConditions lists all conditions of a single node
+type NodeConditions struct {
+	// Node is the name of the node
+	Node string `json:"node"`
+	// Conditions are all conditions reported by the node
+	Conditions []v1.NodeCondition `json:"conditions"`
+}
 
 // Check validates the status of kubernetes components
 func (r *nodeStatusChecker) Check(ctx context.Context, reporter Reporter) {
@@ -106,38 +268,43 @@ func (r *nodeStatusChecker) Check(ctx context.Context, reporter Reporter) {
 	}
 
 	if len(nodes.Items) != 1 {
-		reporter.Add(NewProbeFromErr(r.Name(), "",
-			fmt.Errorf("node %q not found", r.nodeName)))
+		probe := NewProbeFromErr(r.Name(), "", fmt.Errorf("node %q not found", r.nodeName))
+		probe.Code = NodeNotFoundCode
+		reporter.Add(probe)
 		return
 	}
 
-	node := nodes.Items[0]
-	var failureCondition *v1.NodeCondition
+	reporter.Add(nodeProbe(r.Name(), nodes.Items[0]))
+}
+
+// nodeProbe returns the probe of the node which fails when the node is not ready
+// or doesn't report the Ready condition at all
+func nodeProbe(checker string, node v1.Node) *Probe {
+	conditions := NodeConditions{Node: node.Name, Conditions: node.Status.Conditions}
+	detail := "no Ready condition"
 	for _, condition := range node.Status.Conditions {
 		if condition.Type != v1.NodeReady {
 			continue
 		}
-		if condition.Status != v1.ConditionTrue && node.Name == r.nodeName {
-			failureCondition = &condition
-			break
+		if condition.Status == v1.ConditionTrue {
+			return &Probe{
+				Checker:     checker,
+				Status:      ProbeRunning,
+				CheckerData: conditions,
+			}
 		}
+		detail = formatCondition(condition)
+		break
 	}
 
-	if failureCondition == nil {
-		reporter.Add(&Probe{
-			Checker: r.Name(),
-			Status:  ProbeRunning,
-		})
-		return
+	return &Probe{
+		Checker:     checker,
+		Status:      ProbeFailed,
+		Severity:    ProbeWarning,
+		Detail:      detail,
+		Error:       "Node is not ready",
+		CheckerData: conditions,
 	}
-
-	reporter.Add(&Probe{
-		Checker:  r.Name(),
-		Status:   ProbeFailed,
-		Severity: ProbeWarning,
-		Detail:   formatCondition(*failureCondition),
-		Error:    "Node is not ready",
-	})
 }
 
 type nodeLister interface {
@@ -168,4 +335,6 @@ const (
 	NodeStatusCheckerID = "nodestatus"
 	// NodesStatusCheckerID identifies the checker that validates node availability in a cluster
 	NodesStatusCheckerID = "nodesstatus"
+	// NodeNotFoundCode is the code of the node status probe when the node doesn't exist
+	NodeNotFoundCode = "not_found"
 )
@@ -0,0 +1,146 @@
+package runner
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/mateuszdyminski/k8s-status/pkg/config"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kube "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+func testNodes() []map[string]interface{} {
+	node := func(name, ready string) map[string]interface{} {
+		return map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Node",
+			"metadata":   map[string]interface{}{"name": name},
+			"status": map[string]interface{}{
+				"conditions": []interface{}{
+					map[string]interface{}{"type": "Ready", "status": ready, "reason": "KubeletReady"},
+					map[string]interface{}{"type": "DiskPressure", "status": "False"},
+				},
+			},
+		}
+	}
+	return []map[string]interface{}{node("node-1", "True"), node("node-2", "False")}
+}
+
+func TestNodeProbeWithoutReadyCondition(t *testing.T) {
+	node := v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Status:     v1.NodeStatus{Conditions: []v1.NodeCondition{{Type: v1.NodeDiskPressure, Status: v1.ConditionFalse}}},
+	}
+
+	probe := nodeProbe("nodestatus/node-1", node)
+	if probe.Status != ProbeFailed || probe.Detail != "no Ready condition" {
+		t.Errorf("expected failed probe of node without Ready condition, got %+v", probe)
+	}
+}
+
+func TestNodeHealth(t *testing.T) {
+	client := newFakeClient(t, testNodes(), nil)
+	runner, err := NewRunner(KubeConfig{Client: client}, &config.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		node   string
+		status ClusterStatusType
+		probe  ProbeType
+	}{
+		{node: "node-1", status: ClusterHealthy, probe: ProbeRunning},
+		{node: "node-2", status: ClusterDegraded, probe: ProbeFailed},
+		{node: "node-3", status: ClusterFailed, probe: ProbeFailed},
+	}
+
+	for _, test := range tests {
+		result := runner.NodeHealth(context.Background(), test.node)
+		if result.Status != test.status {
+			t.Errorf("%s: expected status %s, got %s", test.node, test.status, result.Status)
+		}
+		if status := probeStatuses(result)["nodestatus/"+test.node]; status != test.probe {
+			t.Errorf("%s: expected probe %s, got %s", test.node, test.probe, status)
+		}
+	}
+
+	result := runner.NodeHealth(context.Background(), "node-3")
+	if len(result.Errors) != 1 || result.Errors[0].Code != NodeNotFoundCode {
+		t.Errorf("expected probe of unknown node with code %s, got %+v", NodeNotFoundCode, result.Errors)
+	}
+}
+
+func TestNodesHealthReportsAllConditions(t *testing.T) {
+	var requests int32
+	cluster := &fakeCluster{objects: testNodes()}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		cluster.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	client, err := kube.NewForConfig(&rest.Config{Host: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	runner, err := NewRunner(KubeConfig{Client: client}, &config.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := runner.NodesHealth(context.Background())
+	if len(result.Oks) != 1 || len(result.Errors) != 1 {
+		t.Fatalf("expected one ok and one failed node, got %v %v", result.Oks, result.Errors)
+	}
+
+	conditions, ok := result.Oks[0].Data.(NodeConditions)
+	if !ok {
+		t.Fatalf("unexpected checker data: %#v", result.Oks[0].Data)
+	}
+	if conditions.Node != "node-1" || len(conditions.Conditions) != 2 {
+		t.Errorf("expected all conditions of node-1, got %+v", conditions)
+	}
+	if requests := atomic.LoadInt32(&requests); requests != 1 {
+		t.Errorf("expected nodes to be listed once, got %d requests", requests)
+	}
+}
+
+func TestNodesCheckerValidatesSeverity(t *testing.T) {
+	tests := []struct {
+		params map[string]interface{}
+		err    string
+	}{
+		{params: map[string]interface{}{"unschedulable": map[string]interface{}{"threshold": 1, "severity": "critical"}}},
+		{params: map[string]interface{}{"unschedulable": map[string]interface{}{"threshold": 1}}},
+		{
+			params: map[string]interface{}{"unschedulable": map[string]interface{}{"threshold": 1, "severity": "critcal"}},
+			err:    `unknown severity "critcal" of rule unschedulable`,
+		},
+		{
+			params: map[string]interface{}{"conditions": map[string]interface{}{"DiskPressure": map[string]interface{}{"threshold": 1, "severity": "error"}}},
+			err:    `unknown severity "error" of rule DiskPressure`,
+		},
+		{
+			params: map[string]interface{}{"noExecuteTaints": map[string]interface{}{"threshold": 1, "severity": "Warning"}},
+			err:    `unknown severity "Warning" of rule noExecuteTaints`,
+		},
+	}
+
+	kubeConfig := KubeConfig{Client: newFakeClient(t, nil, nil)}
+	for _, test := range tests {
+		_, err := NewCheckerFromSpec(kubeConfig, config.CheckerSpec{Type: "nodes", Name: "nodes", Params: test.params})
+		if test.err == "" && err != nil {
+			t.Errorf("%v: unexpected error: %s", test.params, err)
+		}
+		if test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)) {
+			t.Errorf("%v: expected error %q, got %v", test.params, test.err, err)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+// defaultBadContainerReasons are container waiting or termination reasons reported by the pods checker
+var defaultBadContainerReasons = []string{"CrashLoopBackOff", "ImagePullBackOff", "ErrImagePull", "OOMKilled", "CreateContainerConfigError"}
+
+// PodsParams configures the pods checker
+type PodsParams struct {
+	// Namespaces are namespaces of scanned pods, all namespaces when empty
+	Namespaces []string `json:"namespaces"`
+	// Selector is the label selector of scanned pods
+	Selector string `json:"selector"`
+	// Reasons are container waiting or termination reasons reported as problems
+	Reasons []string `json:"reasons"`
+	// RestartThreshold is the number of restarts within the window which is reported as a restart storm, 0 disables it
+	RestartThreshold int32 `json:"restartThreshold"`
+	// RestartWindow is the window in which restarts are counted
+	RestartWindow string `json:"restartWindow"`
+}
+
+// ContainerProblem is a problem of a single container reported by the pods checker
+type ContainerProblem struct {
+	Namespace string `json:"namespace"`
+	Pod       string `json:"pod"`
+	Container string `json:"container"`
+	// Reason is the waiting or termination reason, or RestartStorm
+	Reason string `json:"reason"`
+	// Restarts is the restart count of the container
+	Restarts int32 `json:"restarts"`
+	// RecentRestarts is the number of restarts within the restart window
+	RecentRestarts int32 `json:"recentRestarts,omitempty"`
+	// LastTerminationMessage is the message of the last termination of the container
+	LastTerminationMessage string `json:"lastTerminationMessage,omitempty"`
+}
+
+// restartSample is the restart count of a container observed at the given time
+type restartSample struct {
+	at       time.Time
+	restarts int32
+}
+
+// containerKey identifies a container of a specific pod instance
+type containerKey struct {
+	pod       types.UID
+	container string
+}
+
+// NewPodsChecker returns a Checker that reports crash looping containers and restart storms
+func NewPodsChecker(config KubeConfig, name string, params PodsParams) (Checker, error) {
+	window, err := time.ParseDuration(params.RestartWindow)
+	if params.RestartWindow == "" {
+		window, err = 10*time.Minute, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid restart window: %s", err)
+	}
+
+	reasons := params.Reasons
+	if len(reasons) == 0 {
+		reasons = defaultBadContainerReasons
+	}
+
+	namespaces := params.Namespaces
+	if len(namespaces) == 0 {
+		namespaces = []string{metav1.NamespaceAll}
+	}
+
+	return &podsChecker{
+		name:             name,
+		client:           config.Client.CoreV1(),
+		namespaces:       namespaces,
+		selector:         params.Selector,
+		reasons:          reasons,
+		restartThreshold: params.RestartThreshold,
+		restartWindow:    window,
+		history:          make(map[containerKey][]restartSample),
+		now:              time.Now,
+	}, nil
+}
+
+// podsChecker scans pods for containers with bad states. It remembers restart counts
+// between runs to detect containers which restart too often within the window.
+type podsChecker struct {
+	name             string
+	client           corev1.CoreV1Interface
+	namespaces       []string
+	selector         string
+	reasons          []string
+	restartThreshold int32
+	restartWindow    time.Duration
+
+	mu      sync.Mutex
+	history map[containerKey][]restartSample
+	now     func() time.Time
+}
+
+// Name returns the name of this checker
+func (r *podsChecker) Name() string { return r.name }
+
+// Check validates the states of containers in scanned pods
+func (r *podsChecker) Check(ctx context.Context, reporter Reporter) {
+	var pods []v1.Pod
+	for _, namespace := range r.namespaces {
+		list, err := r.client.Pods(namespace).List(metav1.ListOptions{LabelSelector: r.selector})
+		if err != nil {
+			reporter.Add(NewProbeFromErr(r.Name(), "failed to query pods", err))
+			return
+		}
+		pods = append(pods, list.Items...)
+	}
+
+	problems := r.problems(pods)
+	if len(problems) == 0 {
+		reporter.Add(&Probe{
+			Checker: r.Name(),
+			Status:  ProbeRunning,
+		})
+		return
+	}
+
+	for _, problem := range problems {
+		reporter.Add(&Probe{
+			Checker: fmt.Sprintf("%s/%s/%s/%s", r.Name(), problem.Namespace, problem.Pod, problem.Container),
+			Status:  ProbeFailed,
+			Detail:  problem.Reason,
+			Error: fmt.Sprintf("container %s of pod %s/%s: %s",
+				problem.Container, problem.Namespace, problem.Pod, problem.Reason),
+			CheckerData: problem,
+		})
+	}
+}
+
+// problems returns problems of all containers and updates the restart history
+func (r *podsChecker) problems(pods []v1.Pod) []ContainerProblem {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	seen := make(map[containerKey]bool)
+
+	var problems []ContainerProblem
+	for _, pod := range pods {
+		statuses := append(append([]v1.ContainerStatus(nil), pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+		for _, status := range statuses {
+			key := containerKey{pod: pod.UID, container: status.Name}
+			seen[key] = true
+
+			problem := ContainerProblem{
+				Namespace: pod.Namespace,
+				Pod:       pod.Name,
+				Container: status.Name,
+				Restarts:  status.RestartCount,
+			}
+			if status.LastTerminationState.Terminated != nil {
+				problem.LastTerminationMessage = status.LastTerminationState.Terminated.Message
+			}
+
+			recent := r.recordRestarts(key, status.RestartCount, now)
+			if reason := r.badReason(status); reason != "" {
+				problem.Reason = reason
+				problem.RecentRestarts = recent
+				problems = append(problems, problem)
+			} else if r.restartThreshold > 0 && recent > r.restartThreshold {
+				problem.Reason = "RestartStorm"
+				problem.RecentRestarts = recent
+				problems = append(problems, problem)
+			}
+		}
+	}
+
+	// forget containers of deleted pods
+	for key := range r.history {
+		if !seen[key] {
+			delete(r.history, key)
+		}
+	}
+
+	return problems
+}
+
+// recordRestarts stores the restart count and returns the number of restarts within the window
+func (r *podsChecker) recordRestarts(key containerKey, restarts int32, now time.Time) int32 {
+	samples := append(r.history[key], restartSample{at: now, restarts: restarts})
+
+	// keep the newest sample older than the window as the baseline
+	start := 0
+	for i, sample := range samples {
+		if now.Sub(sample.at) >= r.restartWindow {
+			start = i
+		}
+	}
+	samples = samples[start:]
+	r.history[key] = samples
+
+	return restarts - samples[0].restarts
+}
+
+// badReason returns the configured reason found in the container state
+func (r *podsChecker) badReason(status v1.ContainerStatus) string {
+	var reasons []string
+	if status.State.Waiting != nil {
+		reasons = append(reasons, status.State.Waiting.Reason)
+	}
+	if status.State.Terminated != nil {
+		reasons = append(reasons, status.State.Terminated.Reason)
+	}
+	if status.LastTerminationState.Terminated != nil && status.State.Running == nil {
+		reasons = append(reasons, status.LastTerminationState.Terminated.Reason)
+	}
+
+	for _, reason := range reasons {
+		for _, bad := range r.reasons {
+			if strings.EqualFold(reason, bad) {
+				return reason
+			}
+		}
+	}
+	return ""
+}
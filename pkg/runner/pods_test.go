@@ -0,0 +1,81 @@
+package runner
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func testPod(restarts int32, state v1.ContainerState) v1.Pod {
+	return v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "api-0", Namespace: "default", UID: "uid-1"},
+		Status: v1.PodStatus{
+			ContainerStatuses: []v1.ContainerStatus{{
+				Name:         "api",
+				RestartCount: restarts,
+				State:        state,
+				LastTerminationState: v1.ContainerState{
+					Terminated: &v1.ContainerStateTerminated{Reason: "Error", Message: "panic: boom"},
+				},
+			}},
+		},
+	}
+}
+
+func TestPodsCheckerDetectsBadContainerStates(t *testing.T) {
+	checker, err := NewPodsChecker(KubeConfig{Client: newFakeClient(t, nil, nil)}, "pods", PodsParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	waiting := v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}}
+	problems := checker.(*podsChecker).problems([]v1.Pod{testPod(3, waiting)})
+	if len(problems) != 1 {
+		t.Fatalf("expected one problem, got %v", problems)
+	}
+	if problems[0].Reason != "CrashLoopBackOff" || problems[0].LastTerminationMessage != "panic: boom" {
+		t.Errorf("unexpected problem: %+v", problems[0])
+	}
+}
+
+func TestPodsCheckerDetectsRestartStorm(t *testing.T) {
+	checker, err := NewPodsChecker(KubeConfig{Client: newFakeClient(t, nil, nil)}, "pods", PodsParams{
+		RestartThreshold: 3,
+		RestartWindow:    "10m",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pods := checker.(*podsChecker)
+
+	now := time.Now()
+	pods.now = func() time.Time { return now }
+	running := v1.ContainerState{Running: &v1.ContainerStateRunning{}}
+
+	steps := []struct {
+		after    time.Duration
+		restarts int32
+		storm    bool
+	}{
+		{after: 0, restarts: 10},
+		{after: 5 * time.Minute, restarts: 12},
+		{after: 5 * time.Minute, restarts: 14, storm: true},
+		{after: 5 * time.Minute, restarts: 15},
+		{after: 15 * time.Minute, restarts: 15},
+	}
+
+	for i, step := range steps {
+		now = now.Add(step.after)
+		problems := pods.problems([]v1.Pod{testPod(step.restarts, running)})
+		if storm := len(problems) == 1 && problems[0].Reason == "RestartStorm"; storm != step.storm {
+			t.Errorf("step %d: expected restart storm %v, got %v", i, step.storm, problems)
+		}
+	}
+
+	pods.problems(nil)
+	if len(pods.history) != 0 {
+		t.Errorf("history of deleted pods should be forgotten, got %v", pods.history)
+	}
+}
@@ -1,5 +1,7 @@
 package runner
 
+import "time"
+
 type NodeStatusType string
 
 const (
@@ -8,6 +10,18 @@ const (
 	NodeStatusDegraded NodeStatusType = "degraded"
 )
 
+// ClusterStatusType is the summarized status of the cluster
+type ClusterStatusType string
+
+const (
+	// ClusterHealthy means that all probes are running
+	ClusterHealthy ClusterStatusType = "healthy"
+	// ClusterDegraded means that only probes with warning severity failed
+	ClusterDegraded ClusterStatusType = "degraded"
+	// ClusterFailed means that at least one critical probe failed
+	ClusterFailed ClusterStatusType = "failed"
+)
+
 type ProbeType string
 
 const (
@@ -15,6 +29,8 @@ const (
 	ProbeRunning    ProbeType = "running"
 	ProbeFailed     ProbeType = "failed"
 	ProbeTerminated ProbeType = "terminated"
+	// ProbeSkipped denotes a checker not run because its dependency failed
+	ProbeSkipped ProbeType = "skipped"
 )
 
 // Severity defines the severity of the probe.
@@ -46,11 +62,17 @@ type Probe struct {
 	CheckerData interface{} `json:"checkerData"`
 	// Severity is the severity of the probe
 	Severity ProbeSeverity `json:"severity"`
+
+	// source is the name of the top-level checker which reported the probe
+	source string
 }
 
 type FinalProbe struct {
-	// Status is the result of the probe
-	Status ProbeType `json:"status"`
+	// Status is the summarized status of the cluster
+	Status ClusterStatusType `json:"status"`
+
+	// Drivers are the names of checkers which failed probes determined the status
+	Drivers []string `json:"drivers"`
 
 	// Config is the result of check of configuration in ConfigMap.
 	Config SingleFinalProbe `json:"config"`
@@ -60,11 +82,30 @@ type FinalProbe struct {
 
 	// Oks is the ok response
 	Oks []SingleFinalProbe `json:"oks"`
+
+	// Skipped are checkers not run because their dependencies failed
+	Skipped []SingleFinalProbe `json:"skipped"`
+
+	// CheckedAt is the time when the checks were completed
+	CheckedAt time.Time `json:"checkedAt"`
+
+	// Age is the time elapsed since the checks were completed
+	Age string `json:"age,omitempty"`
+
+	// NextRunAt is the time of the next scheduled run of checks
+	NextRunAt time.Time `json:"nextRunAt"`
+
+	// probes are the probes the status was aggregated from, they are kept for subsets of the status
+	probes []*Probe
 }
 
 type SingleFinalProbe struct {
-	Description string      `json:"description"`
-	Data        interface{} `json:"data"`
+	Checker     string        `json:"checker"`
+	Status      ProbeType     `json:"status"`
+	Description string        `json:"description"`
+	Code        string        `json:"code,omitempty"`
+	Severity    ProbeSeverity `json:"severity,omitempty"`
+	Data        interface{}   `json:"data"`
 }
 
 func (m *Probe) Reset() { *m = Probe{} }
@@ -0,0 +1,265 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mateuszdyminski/k8s-status/pkg/config"
+)
+
+// Params are the checker type specific parameters from the checker config entry
+type Params map[string]interface{}
+
+// Decode decodes params into the provided struct using its json tags,
+// unknown params are rejected so misspelled ones are not ignored
+func (p Params) Decode(into interface{}) error {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(into)
+}
+
+// CheckerFactory creates a checker with the given name from its params
+type CheckerFactory func(kubeConfig KubeConfig, name string, params Params) (Checker, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]CheckerFactory)
+)
+
+// RegisterCheckerType makes checker type available in the checkers config.
+// It panics if the type is registered twice.
+func RegisterCheckerType(typeName string, factory CheckerFactory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if _, ok := registry[typeName]; ok {
+		panic(fmt.Sprintf("checker type %s registered twice", typeName))
+	}
+	registry[typeName] = factory
+}
+
+// NewCheckerFromSpec creates the checker described by the checker spec
+func NewCheckerFromSpec(kubeConfig KubeConfig, spec config.CheckerSpec) (Checker, error) {
+	registryMu.RLock()
+	factory, ok := registry[spec.Type]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown checker type: %s", spec.Type)
+	}
+
+	checker, err := factory(kubeConfig, spec.Name, Params(spec.Params))
+	if err != nil {
+		return nil, fmt.Errorf("can't create checker %s of type %s. err: %s", spec.Name, spec.Type, err)
+	}
+
+	severity := ProbeSeverity(spec.Severity)
+	switch severity {
+	case "", ProbeCritical, ProbeWarning:
+	default:
+		return nil, fmt.Errorf("checker %s has unknown severity: %s", spec.Name, spec.Severity)
+	}
+
+	return &configuredChecker{
+		Checker:   checker,
+		typeName:  spec.Type,
+		severity:  severity,
+		timeout:   spec.Timeout.Duration,
+		dependsOn: spec.DependsOn,
+		tags:      spec.Tags,
+	}, nil
+}
+
+// configuredChecker decorates a checker with settings from its checker spec
+type configuredChecker struct {
+	Checker
+	typeName  string
+	severity  ProbeSeverity
+	timeout   time.Duration
+	dependsOn []string
+	tags      []string
+}
+
+// Timeout returns the checker specific timeout
+func (c *configuredChecker) Timeout() time.Duration { return c.timeout }
+
+// DependsOn returns names of checkers which must succeed before this one runs
+func (c *configuredChecker) DependsOn() []string { return c.dependsOn }
+
+// Tags returns tags of the checker
+func (c *configuredChecker) Tags() []string { return c.tags }
+
+// Type returns the registered type of the checker
+func (c *configuredChecker) Type() string { return c.typeName }
+
+// typedChecker is a checker which knows its registered type
+type typedChecker interface {
+	Type() string
+}
+
+// checkerType returns the registered type of the checker, it's empty for checkers not built from specs
+func checkerType(checker Checker) string {
+	if t, ok := checker.(typedChecker); ok {
+		return t.Type()
+	}
+	return ""
+}
+
+// Check runs the wrapped checker and sets configured severity on its failed probes
+func (c *configuredChecker) Check(ctx context.Context, reporter Reporter) {
+	if c.severity == "" {
+		c.Checker.Check(ctx, reporter)
+		return
+	}
+
+	var probes Probes
+	c.Checker.Check(ctx, &probes)
+	for _, probe := range probes.GetProbes() {
+		if probe.Status == ProbeFailed {
+			probe.Severity = c.severity
+		}
+		reporter.Add(probe)
+	}
+}
+
+func init() {
+	RegisterCheckerType(clusterConfigType, func(kubeConfig KubeConfig, name string, params Params) (Checker, error) {
+		var p ClusterConfigParams
+		if err := params.Decode(&p); err != nil {
+			return nil, err
+		}
+		return NewClusterConfigChecker(kubeConfig, name, p)
+	})
+
+	RegisterCheckerType("componentstatus", func(kubeConfig KubeConfig, name string, params Params) (Checker, error) {
+		var p struct {
+			Component string `json:"component"`
+		}
+		if err := params.Decode(&p); err != nil {
+			return nil, err
+		}
+		if p.Component == "" {
+			p.Component = name
+		}
+		return componentServerHealth(kubeConfig, name, p.Component), nil
+	})
+
+	RegisterCheckerType("nodes", func(kubeConfig KubeConfig, name string, params Params) (Checker, error) {
+		rules := DefaultNodesRules(0)
+		if err := params.Decode(&rules); err != nil {
+			return nil, err
+		}
+		if err := rules.validate(); err != nil {
+			return nil, err
+		}
+		return NewNodesStatusChecker(kubeConfig, name, rules), nil
+	})
+
+	RegisterCheckerType("apiserver", func(kubeConfig KubeConfig, name string, params Params) (Checker, error) {
+		var p struct {
+			Endpoints []string `json:"endpoints"`
+		}
+		if err := params.Decode(&p); err != nil {
+			return nil, err
+		}
+		return NewAPIServerChecker(kubeConfig, name, p.Endpoints), nil
+	})
+
+	RegisterCheckerType("workloads", func(kubeConfig KubeConfig, name string, params Params) (Checker, error) {
+		var p WorkloadsParams
+		if err := params.Decode(&p); err != nil {
+			return nil, err
+		}
+		if p.hasNames() && p.Namespace == "" {
+			return nil, fmt.Errorf("namespace param is required for workloads selected by names")
+		}
+		if p.MinAvailable != nil && (*p.MinAvailable < 0 || *p.MinAvailable > 100) {
+			return nil, fmt.Errorf("minAvailable param must be a percentage, got %d", *p.MinAvailable)
+		}
+		return NewWorkloadsChecker(kubeConfig, name, p), nil
+	})
+
+	RegisterCheckerType("pods", func(kubeConfig KubeConfig, name string, params Params) (Checker, error) {
+		var p PodsParams
+		if err := params.Decode(&p); err != nil {
+			return nil, err
+		}
+		return NewPodsChecker(kubeConfig, name, p)
+	})
+
+	RegisterCheckerType("dns", func(kubeConfig KubeConfig, name string, params Params) (Checker, error) {
+		var p DNSParams
+		if err := params.Decode(&p); err != nil {
+			return nil, err
+		}
+		return NewDNSChecker(kubeConfig, name, p)
+	})
+
+	RegisterCheckerType("endpoints", func(kubeConfig KubeConfig, name string, params Params) (Checker, error) {
+		var p struct {
+			Services []ServiceEndpointsParams `json:"services"`
+		}
+		if err := params.Decode(&p); err != nil {
+			return nil, err
+		}
+		if len(p.Services) == 0 {
+			return nil, fmt.Errorf("services param is required")
+		}
+		return NewServiceEndpointsChecker(kubeConfig, name, p.Services)
+	})
+
+	RegisterCheckerType("http", func(kubeConfig KubeConfig, name string, params Params) (Checker, error) {
+		var p HTTPParams
+		if err := params.Decode(&p); err != nil {
+			return nil, err
+		}
+		return NewHTTPChecker(name, p)
+	})
+
+	RegisterCheckerType("tcp", func(kubeConfig KubeConfig, name string, params Params) (Checker, error) {
+		var p TCPParams
+		if err := params.Decode(&p); err != nil {
+			return nil, err
+		}
+		return NewTCPChecker(name, p)
+	})
+
+	RegisterCheckerType("certificates", func(kubeConfig KubeConfig, name string, params Params) (Checker, error) {
+		var p CertificatesParams
+		if err := params.Decode(&p); err != nil {
+			return nil, err
+		}
+		return NewCertificatesChecker(kubeConfig, name, p)
+	})
+
+	RegisterCheckerType("storage", func(kubeConfig KubeConfig, name string, params Params) (Checker, error) {
+		var p StorageParams
+		if err := params.Decode(&p); err != nil {
+			return nil, err
+		}
+		return NewStorageChecker(kubeConfig, name, p)
+	})
+
+	RegisterCheckerType("events", func(kubeConfig KubeConfig, name string, params Params) (Checker, error) {
+		var p EventsParams
+		if err := params.Decode(&p); err != nil {
+			return nil, err
+		}
+		return NewEventsChecker(kubeConfig, name, p)
+	})
+
+	RegisterCheckerType("capacity", func(kubeConfig KubeConfig, name string, params Params) (Checker, error) {
+		var p CapacityParams
+		if err := params.Decode(&p); err != nil {
+			return nil, err
+		}
+		return NewCapacityChecker(kubeConfig, name, p)
+	})
+}
@@ -1,6 +1,9 @@
 package runner
 
-import "context"
+import (
+	"context"
+	"sync"
+)
 
 // Reporter defines an obligation to report structured errors.
 type Reporter interface {
@@ -13,32 +16,43 @@ type Reporter interface {
 }
 
 // Probes is a list of probes.
-// It implements the Reporter interface.
-type Probes []*Probe
+// It implements the Reporter interface and is safe for concurrent use.
+type Probes struct {
+	mu     sync.Mutex
+	probes []*Probe
+}
 
 // Add adds a health probe for a specific node.
 // Implements Reporter
 func (r *Probes) Add(probe *Probe) {
-	*r = append(*r, probe)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.probes = append(r.probes, probe)
 }
 
 // Status retrieves the collected status after executing all checks.
 // Implements Reporter
-func (r Probes) GetProbes() []*Probe {
-	return []*Probe(r)
+func (r *Probes) GetProbes() []*Probe {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	probes := make([]*Probe, len(r.probes))
+	copy(probes, r.probes)
+	return probes
 }
 
 // NumProbes returns the number of probes this reporter contains
 // Implements Reporter
-func (r Probes) NumProbes() int {
-	return len(r)
+func (r *Probes) NumProbes() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.probes)
 }
 
 // GetFailed returns all probes that reported an error
-func (r Probes) GetFailed() []*Probe {
+func (r *Probes) GetFailed() []*Probe {
 	var failed []*Probe
 
-	for _, probe := range r {
+	for _, probe := range r.GetProbes() {
 		if probe.Status == ProbeFailed {
 			failed = append(failed, probe)
 		}
@@ -48,9 +62,9 @@ func (r Probes) GetFailed() []*Probe {
 }
 
 // Status computes the node status based on collected probes.
-func (r Probes) Status() NodeStatusType {
+func (r *Probes) Status() NodeStatusType {
 	result := NodeStatusRunning
-	for _, probe := range r {
+	for _, probe := range r.GetProbes() {
 		if probe.Status == ProbeFailed {
 			result = NodeStatusDegraded
 			break
@@ -63,6 +77,9 @@ func (r Probes) Status() NodeStatusType {
 type Checker interface {
 	Name() string
 	// Check runs a health check and records any errors into the specified reporter.
+	// Implementations must honor the context and return as soon as it's done. A call
+	// which doesn't return keeps its concurrency slot and the checker is not run again
+	// until it returns.
 	Check(context.Context, Reporter)
 }
 
@@ -74,6 +91,42 @@ func (r *Checkers) AddChecker(checker Checker) {
 	*r = append(*r, checker)
 }
 
+// checkerGroup runs several checkers as a single one, so one config entry can check many objects
+type checkerGroup struct {
+	Checkers
+	name string
+	// parallel runs checkers of the group concurrently, their probes are reported in order of checkers
+	parallel bool
+}
+
+// Name returns the name of this checker
+func (g *checkerGroup) Name() string { return g.name }
+
+// Check runs all checkers of the group successively or concurrently when the group is parallel
+func (g *checkerGroup) Check(ctx context.Context, reporter Reporter) {
+	if !g.parallel {
+		for _, checker := range g.Checkers {
+			checker.Check(ctx, reporter)
+		}
+		return
+	}
+
+	probes := make([]Probes, len(g.Checkers))
+	var wg sync.WaitGroup
+	for i, checker := range g.Checkers {
+		wg.Add(1)
+		go func(checker Checker, probes *Probes) {
+			defer wg.Done()
+			checker.Check(ctx, probes)
+		}(checker, &probes[i])
+	}
+	wg.Wait()
+
+	for i := range probes {
+		AddFrom(reporter, &probes[i])
+	}
+}
+
 // CheckerRepository represents a collection of checkers.
 type CheckerRepository interface {
 	AddChecker(checker Checker)
@@ -2,25 +2,51 @@ package runner
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/mateuszdyminski/k8s-status/pkg/config"
 	"github.com/rs/zerolog/log"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/rest"
 )
 
 // Runner stores configured checkers and provides interface to configure
 // and run them
 type Runner struct {
 	Checkers
-	cfg *config.Config
+	cfg        *config.Config
+	kubeConfig KubeConfig
+
+	// concurrency limits the number of checkers running at the same time
+	concurrency int
+	// timeout is the deadline given to every single checker
+	timeout time.Duration
+	// metrics are updated on every run, nil disables them
+	metrics *Metrics
+
+	callsMu sync.Mutex
+	// slots limits checker calls of all runs, a slot is held until the call returns
+	// even when the checker timed out, nil when concurrency is not limited
+	slots chan struct{}
+	// inFlight are names of checkers whose previous call has not returned yet
+	inFlight map[string]bool
 }
 
-// NewRunner creates Runner with checks configured using provided options
+// timeoutChecker is a checker with its own timeout
+type timeoutChecker interface {
+	Timeout() time.Duration
+}
+
+// defaultCheckerTimeout is used when no checker timeout is configured
+const defaultCheckerTimeout = 10 * time.Second
+
+// NewRunnerWithCfg creates Runner with kubernetes client created from config
 func NewRunnerWithCfg(cfg *config.Config) (*Runner, error) {
-	// creates the in-cluster config
-	config, err := rest.InClusterConfig()
+	// creates the in-cluster config or the config from kubeconfig
+	config, err := restConfig(cfg)
 	if err != nil {
 		return nil, err
 	}
@@ -31,62 +57,347 @@ func NewRunnerWithCfg(cfg *config.Config) (*Runner, error) {
 		return nil, err
 	}
 
-	kubeConfig := KubeConfig{Client: clientset}
+	return NewRunner(KubeConfig{Client: clientset, RestConfig: config}, cfg)
+}
+
+// NewRunner creates Runner with checks configured using provided options
+func NewRunner(kubeConfig KubeConfig, cfg *config.Config) (*Runner, error) {
+	runner := &Runner{
+		cfg:         cfg,
+		kubeConfig:  kubeConfig,
+		concurrency: cfg.CheckerConcurrency,
+		timeout:     time.Duration(cfg.CheckerTimeout) * time.Second,
+	}
+
+	for _, spec := range cfg.Checkers {
+		if !spec.IsEnabled() {
+			log.Info().Msgf("checker %s is disabled", spec.Name)
+			continue
+		}
 
-	runner := &Runner{cfg: cfg}
-	runner.AddChecker(KubeClusterConfig(kubeConfig, cfg))
-	runner.AddChecker(KubeEtcdHealth(kubeConfig))
-	runner.AddChecker(KubeSchedulerHealth(kubeConfig))
-	runner.AddChecker(KubeControllerManagerHealth(kubeConfig))
-	runner.AddChecker(NodesStatusHealth(kubeConfig, cfg.KubeNodesReadyThreshold))
+		checker, err := NewCheckerFromSpec(kubeConfig, spec)
+		if err != nil {
+			return nil, err
+		}
+		runner.AddChecker(checker)
+	}
+
+	if err := validateDependencies(runner.Checkers); err != nil {
+		return nil, err
+	}
 	return runner, nil
 }
 
-// Run runs all checks successively and reports general cluster status
+// SetMetrics sets metrics updated on every run, it must be called before the first run
+func (c *Runner) SetMetrics(metrics *Metrics) {
+	c.metrics = metrics
+}
+
+// Run runs all checks concurrently and reports general cluster status.
+// Checkers whose dependencies failed are skipped.
 func (c *Runner) Run(ctx context.Context) *FinalProbe {
-	var probes Probes
+	probes := c.runCheckers(ctx, c.Checkers, c.metrics)
+	result := c.finalHealth(probes)
+	c.metrics.observeRun(result, probes)
+	return result
+}
+
+// NodeHealth runs the single node checker and reports status of the node
+func (c *Runner) NodeHealth(ctx context.Context, nodeName string) *FinalProbe {
+	return c.finalHealth(c.runCheckers(ctx, Checkers{NewNodeStatusChecker(c.kubeConfig, nodeName)}, nil))
+}
 
-	for _, c := range c.Checkers {
-		log.Info().Msgf("running checker %s", c.Name())
-		c.Check(ctx, &probes)
+// NodesHealth reports statuses of all nodes in the cluster, the probes of nodes are built
+// from a single list of nodes
+func (c *Runner) NodesHealth(ctx context.Context) *FinalProbe {
+	nodes, err := c.kubeConfig.Client.CoreV1().Nodes().List(metav1.ListOptions{})
+	if err != nil {
+		return c.finalHealth([]*Probe{NewProbeFromErr(NodeStatusCheckerID, "failed to query nodes", err)})
 	}
 
+	probes := make([]*Probe, 0, len(nodes.Items))
+	for _, node := range nodes.Items {
+		probes = append(probes, nodeProbe(fmt.Sprintf("%s/%s", NodeStatusCheckerID, node.Name), node))
+	}
 	return c.finalHealth(probes)
 }
 
-// finalHealth aggregates statuses from all probes into one summarized health status
-func (c *Runner) finalHealth(probes Probes) *FinalProbe {
+// runCheckers runs checkers concurrently and returns their probes, durations and errors
+// of checkers are recorded in metrics when they are not nil
+func (c *Runner) runCheckers(ctx context.Context, checkers Checkers, metrics *Metrics) []*Probe {
+	var probes Probes
+
+	results := make(map[string]*checkerResult, len(checkers))
+	for _, checker := range checkers {
+		results[checker.Name()] = &checkerResult{done: make(chan struct{})}
+	}
+
+	// checkers wait for their dependencies before taking a slot, so independent checkers run in parallel
+	var wg sync.WaitGroup
+	for _, checker := range checkers {
+		wg.Add(1)
+		go func(checker Checker) {
+			defer wg.Done()
+			result := results[checker.Name()]
+			defer close(result.done)
+
+			if rootCause := blockedBy(checker, results); rootCause != "" {
+				log.Info().Msgf("skipping checker %s, %s failed", checker.Name(), rootCause)
+				result.failed, result.rootCause = true, rootCause
+				skipped := newSkippedProbe(checker.Name(), rootCause)
+				skipped.source = checker.Name()
+				probes.Add(skipped)
+				return
+			}
+
+			// the deadline of the checker includes waiting for a slot
+			timeout := c.checkerTimeout(checker)
+			checkerCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			var checkerProbes Probes
+			var duration time.Duration
+			release, err := c.startCall(checkerCtx, checker.Name())
+			switch {
+			case err == errStillRunning:
+				log.Warn().Msgf("skipping checker %s, its previous call is still running", checker.Name())
+				probe := NewProbeFromErr(checker.Name(), "checker is still running", err)
+				probe.Code = "still_running"
+				checkerProbes.Add(probe)
+			case err != nil:
+				log.Warn().Msgf("checker %s did not get a slot within %s", checker.Name(), timeout)
+				checkerProbes.Add(timeoutProbe(checker.Name(), timeout, err))
+			default:
+				// the duration starts once the slot is taken, so it doesn't include waiting for one
+				start := time.Now()
+				c.runChecker(checkerCtx, checker, &checkerProbes, release, timeout)
+				duration = time.Since(start)
+			}
+			for _, probe := range checkerProbes.GetProbes() {
+				probe.source = checker.Name()
+			}
+			metrics.observeChecker(checker.Name(), duration, checkerProbes.GetProbes())
+			if hasCriticalFailure(checkerProbes.GetFailed()) {
+				result.failed, result.rootCause = true, checker.Name()
+			}
+			AddFrom(&probes, &checkerProbes)
+		}(checker)
+	}
+	wg.Wait()
+
+	return probes.GetProbes()
+}
+
+// errStillRunning is returned by startCall when the previous call of the checker has not returned yet
+var errStillRunning = errors.New("previous call of the checker has not returned yet")
+
+// startCall marks the checker as running and takes a concurrency slot, it waits for a free slot
+// until the context is done. It fails with errStillRunning when the previous call of the checker
+// has not returned yet, so a stateful checker never runs twice at the same time.
+// The returned function must be called when the call returns.
+func (c *Runner) startCall(ctx context.Context, name string) (func(), error) {
+	c.callsMu.Lock()
+	if c.inFlight[name] {
+		c.callsMu.Unlock()
+		return nil, errStillRunning
+	}
+	if c.inFlight == nil {
+		c.inFlight = make(map[string]bool)
+	}
+	c.inFlight[name] = true
+	if c.slots == nil && c.concurrency > 0 {
+		c.slots = make(chan struct{}, c.concurrency)
+	}
+	slots := c.slots
+	c.callsMu.Unlock()
+
+	done := func() {
+		c.callsMu.Lock()
+		delete(c.inFlight, name)
+		c.callsMu.Unlock()
+	}
+	if slots == nil {
+		return done, nil
+	}
+
+	select {
+	case slots <- struct{}{}:
+	case <-ctx.Done():
+		done()
+		return nil, ctx.Err()
+	}
+	return func() {
+		<-slots
+		done()
+	}, nil
+}
+
+// checkerTimeout returns the timeout of the checker, the timeout of the runner by default
+func (c *Runner) checkerTimeout(checker Checker) time.Duration {
+	timeout := c.timeout
+	if t, ok := checker.(timeoutChecker); ok && t.Timeout() > 0 {
+		timeout = t.Timeout()
+	}
+	if timeout <= 0 {
+		timeout = defaultCheckerTimeout
+	}
+	return timeout
+}
+
+// timeoutProbe returns the failed probe of a checker which did not finish within the timeout
+func timeoutProbe(checker string, timeout time.Duration, err error) *Probe {
+	probe := NewProbeFromErr(checker, "checker timed out",
+		fmt.Errorf("checker did not finish within %s: %s", timeout, err))
+	probe.Code = "timeout"
+	return probe
+}
+
+// runChecker runs a single checker until the context with its deadline is done. Probes are copied
+// to the reporter only when the checker finishes in time, otherwise a failed probe is reported.
+// A checker which ignores the context keeps running in the background, its late
+// probes are dropped and release is called only when it returns, so it keeps its slot.
+func (c *Runner) runChecker(ctx context.Context, checker Checker, reporter Reporter, release func(), timeout time.Duration) {
+	log.Info().Msgf("running checker %s", checker.Name())
+
+	var probes Probes
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer release()
+		checker.Check(ctx, &probes)
+	}()
+
+	select {
+	case <-done:
+		AddFrom(reporter, &probes)
+	case <-ctx.Done():
+		log.Warn().Msgf("checker %s did not finish within %s", checker.Name(), timeout)
+		reporter.Add(timeoutProbe(checker.Name(), timeout, ctx.Err()))
+	}
+}
+
+// finalHealth aggregates statuses from all probes into one summarized health status and logs it
+func (c *Runner) finalHealth(probes []*Probe) *FinalProbe {
+	clusterHealth := c.aggregate(probes)
+
+	log.Info().Msgf("cluster new health: %#v", *clusterHealth)
+
+	return clusterHealth
+}
+
+// aggregate summarizes statuses from all probes. Any failed critical probe fails the cluster,
+// while failed warning probes only degrade it.
+func (c *Runner) aggregate(probes []*Probe) *FinalProbe {
 	var errors []SingleFinalProbe
 	var oks []SingleFinalProbe
+	var skipped []SingleFinalProbe
 	var config SingleFinalProbe
-	status := ProbeRunning
+	var criticals, warnings []string
+	configChecker := c.configChecker()
 
 	for _, probe := range probes {
+		var single SingleFinalProbe
 		switch probe.Status {
 		case ProbeRunning:
-			if probe.Checker == c.cfg.ConfigCheckerConfigName {
-				config = SingleFinalProbe{Description: fmt.Sprintf("Check %s: OK", probe.Checker), Data: probe.CheckerData}
-			} else {
-				oks = append(oks, SingleFinalProbe{Description: fmt.Sprintf("Check %s: OK", probe.Checker), Data: probe.CheckerData})
+			single = SingleFinalProbe{
+				Checker:     probe.Checker,
+				Status:      probe.Status,
+				Description: fmt.Sprintf("Check %s: OK", probe.Checker),
+				Code:        probe.Code,
+				Data:        probe.CheckerData,
+			}
+		case ProbeSkipped:
+			// skipped probes don't change the status, their root cause already does
+			single = SingleFinalProbe{
+				Checker:     probe.Checker,
+				Status:      probe.Status,
+				Description: fmt.Sprintf("Check %s: %s", probe.Checker, probe.Error),
+				Data:        probe.CheckerData,
 			}
 		default:
-			status = ProbeFailed
-			if probe.Checker == c.cfg.ConfigCheckerConfigName {
-				config = SingleFinalProbe{Description: fmt.Sprintf("Check %s: %s", probe.Checker, probe.Error), Data: probe.CheckerData}
+			severity := failureSeverity(probe)
+			if severity == ProbeWarning {
+				warnings = appendUnique(warnings, probe.Checker)
 			} else {
-				errors = append(errors, SingleFinalProbe{Description: fmt.Sprintf("Check %s: %s", probe.Checker, probe.Error), Data: probe.CheckerData})
+				criticals = appendUnique(criticals, probe.Checker)
 			}
+			single = SingleFinalProbe{
+				Checker:     probe.Checker,
+				Status:      probe.Status,
+				Description: fmt.Sprintf("Check %s: %s", probe.Checker, probe.Error),
+				Code:        probe.Code,
+				Severity:    severity,
+				Data:        probe.CheckerData,
+			}
+		}
+
+		switch {
+		case configChecker != "" && probe.Checker == configChecker:
+			config = single
+		case probe.Status == ProbeRunning:
+			oks = append(oks, single)
+		case probe.Status == ProbeSkipped:
+			skipped = append(skipped, single)
+		default:
+			errors = append(errors, single)
 		}
 	}
 
-	clusterHealth := FinalProbe{
-		Status: status,
-		Config: config,
-		Errors: errors,
-		Oks:    oks,
+	status, drivers := ClusterHealthy, []string(nil)
+	if len(criticals) > 0 {
+		status, drivers = ClusterFailed, criticals
+	} else if len(warnings) > 0 {
+		status, drivers = ClusterDegraded, warnings
 	}
 
-	log.Info().Msgf("cluster new health: %#v", clusterHealth)
+	clusterHealth := FinalProbe{
+		Status:    status,
+		Drivers:   drivers,
+		Config:    config,
+		Errors:    errors,
+		Oks:       oks,
+		Skipped:   skipped,
+		CheckedAt: time.Now(),
+		probes:    probes,
+	}
 
 	return &clusterHealth
 }
+
+// hasCriticalFailure returns true when any of failed probes is critical, warnings don't block dependents
+func hasCriticalFailure(failed []*Probe) bool {
+	for _, probe := range failed {
+		if failureSeverity(probe) == ProbeCritical {
+			return true
+		}
+	}
+	return false
+}
+
+// configChecker returns the name of the first cluster config checker, its main probe
+// is reported as the cluster config whatever the checker is named
+func (c *Runner) configChecker() string {
+	for _, checker := range c.Checkers {
+		if checkerType(checker) == clusterConfigType {
+			return checker.Name()
+		}
+	}
+	return ""
+}
+
+// failureSeverity returns the severity of failed probe, probes without severity are critical
+func failureSeverity(probe *Probe) ProbeSeverity {
+	if probe.Severity == ProbeWarning {
+		return ProbeWarning
+	}
+	return ProbeCritical
+}
+
+func appendUnique(items []string, item string) []string {
+	for _, i := range items {
+		if i == item {
+			return items
+		}
+	}
+	return append(items, item)
+}
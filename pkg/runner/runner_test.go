@@ -0,0 +1,188 @@
+package runner
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mateuszdyminski/k8s-status/pkg/config"
+)
+
+// stubChecker reports a probe with the given status after an optional delay
+type stubChecker struct {
+	name      string
+	status    ProbeType
+	delay     time.Duration
+	dependsOn []string
+	runs      int32
+}
+
+func (s *stubChecker) Name() string { return s.name }
+
+func (s *stubChecker) DependsOn() []string { return s.dependsOn }
+
+func (s *stubChecker) Check(ctx context.Context, reporter Reporter) {
+	atomic.AddInt32(&s.runs, 1)
+	time.Sleep(s.delay)
+	reporter.Add(&Probe{Checker: s.name, Status: s.status, Error: "stub error"})
+}
+
+func newStubRunner(checkers ...Checker) *Runner {
+	return &Runner{
+		Checkers:    Checkers(checkers),
+		cfg:         &config.Config{},
+		concurrency: 2,
+		timeout:     50 * time.Millisecond,
+	}
+}
+
+func TestRunReportsTimeoutOfSlowChecker(t *testing.T) {
+	runner := newStubRunner(
+		&stubChecker{name: "slow", status: ProbeRunning, delay: time.Second},
+		&stubChecker{name: "fast", status: ProbeRunning},
+	)
+
+	start := time.Now()
+	result := runner.Run(context.Background())
+	if took := time.Since(start); took > 500*time.Millisecond {
+		t.Errorf("run should not wait for slow checker, took %s", took)
+	}
+
+	statuses := probeStatuses(result)
+	if statuses["slow"] != ProbeFailed || statuses["fast"] != ProbeRunning {
+		t.Errorf("unexpected probes: %v", statuses)
+	}
+	if len(result.Errors) != 1 || !strings.Contains(result.Errors[0].Description, "did not finish within") {
+		t.Errorf("expected timeout error, got %v", result.Errors)
+	}
+}
+
+func TestRunDoesNotSkipDependentsOfWarning(t *testing.T) {
+	runner := newStubRunner(
+		&configuredChecker{Checker: &stubChecker{name: "nodes", status: ProbeFailed}, severity: ProbeWarning},
+		&stubChecker{name: "pods", status: ProbeFailed, dependsOn: []string{"nodes"}},
+	)
+
+	result := runner.Run(context.Background())
+
+	statuses := probeStatuses(result)
+	if statuses["nodes"] != ProbeFailed || statuses["pods"] != ProbeFailed {
+		t.Errorf("expected dependent of warning to run, got %v", statuses)
+	}
+	if result.Status != ClusterFailed || len(result.Drivers) != 1 || result.Drivers[0] != "pods" {
+		t.Errorf("expected failed status driven by pods, got %s %v", result.Status, result.Drivers)
+	}
+}
+
+func TestRunKeepsSlotOfTimedOutChecker(t *testing.T) {
+	runner := newStubRunner(&stubChecker{name: "slow", status: ProbeRunning, delay: time.Second})
+	runner.concurrency = 1
+	runner.Run(context.Background())
+
+	// the slow call from the previous run still holds the only slot, so the fast checker
+	// times out waiting for it instead of blocking the run
+	runner.AddChecker(&stubChecker{name: "fast", status: ProbeRunning})
+	start := time.Now()
+	result := runner.Run(context.Background())
+	if took := time.Since(start); took > 500*time.Millisecond {
+		t.Errorf("expected run to not wait for the slot of the timed out checker, took %s", took)
+	}
+
+	codes := make(map[string]string)
+	for _, probe := range result.Errors {
+		codes[probe.Checker] = probe.Code
+	}
+	if codes["slow"] != "still_running" || codes["fast"] != "timeout" {
+		t.Errorf("expected slow checker to not run again and fast one to time out waiting for a slot, got %v", codes)
+	}
+}
+
+func TestRunSkipsDependentsOfFailedChecker(t *testing.T) {
+	runner := newStubRunner(
+		&stubChecker{name: "nodes", status: ProbeRunning, dependsOn: []string{"apiserver"}},
+		&stubChecker{name: "pods", status: ProbeRunning, dependsOn: []string{"nodes"}},
+		&stubChecker{name: "apiserver", status: ProbeFailed},
+		&stubChecker{name: "dns", status: ProbeRunning},
+	)
+
+	result := runner.Run(context.Background())
+
+	statuses := probeStatuses(result)
+	expected := map[string]ProbeType{"apiserver": ProbeFailed, "nodes": ProbeSkipped, "pods": ProbeSkipped, "dns": ProbeRunning}
+	for name, status := range expected {
+		if statuses[name] != status {
+			t.Errorf("expected %s to be %s, got %s", name, status, statuses[name])
+		}
+	}
+	for _, skipped := range result.Skipped {
+		if !strings.Contains(skipped.Description, "apiserver") {
+			t.Errorf("skipped probe should point to root cause: %s", skipped.Description)
+		}
+	}
+	if result.Status != ClusterFailed || len(result.Drivers) != 1 || result.Drivers[0] != "apiserver" {
+		t.Errorf("expected failed status driven by apiserver, got %s %v", result.Status, result.Drivers)
+	}
+}
+
+func TestRunReportsConfigCheckerByType(t *testing.T) {
+	runner := newStubRunner(
+		&configuredChecker{Checker: &stubChecker{name: "settings", status: ProbeFailed}, typeName: clusterConfigType},
+		&stubChecker{name: "cluster-config", status: ProbeRunning},
+	)
+	runner.cfg.ConfigCheckerConfigName = "cluster-config"
+
+	result := runner.Run(context.Background())
+	if result.Config.Checker != "settings" || result.Config.Status != ProbeFailed {
+		t.Errorf("expected probe of the cluster config checker in config, got %+v", result.Config)
+	}
+	if len(result.Oks) != 1 || result.Oks[0].Checker != "cluster-config" {
+		t.Errorf("expected checker of another type named like the config in oks, got %+v", result.Oks)
+	}
+}
+
+func TestValidateDependencies(t *testing.T) {
+	tests := []struct {
+		name     string
+		checkers Checkers
+		err      string
+	}{
+		{
+			name:     "valid",
+			checkers: Checkers{&stubChecker{name: "a"}, &stubChecker{name: "b", dependsOn: []string{"a"}}},
+		},
+		{
+			name:     "unknown",
+			checkers: Checkers{&stubChecker{name: "a", dependsOn: []string{"missing"}}},
+			err:      "unknown or disabled checker missing",
+		},
+		{
+			name: "cycle",
+			checkers: Checkers{
+				&stubChecker{name: "a", dependsOn: []string{"c"}},
+				&stubChecker{name: "b", dependsOn: []string{"a"}},
+				&stubChecker{name: "c", dependsOn: []string{"b"}},
+			},
+			err: "dependency cycle",
+		},
+	}
+
+	for _, test := range tests {
+		err := validateDependencies(test.checkers)
+		switch {
+		case test.err == "" && err != nil:
+			t.Errorf("%s: unexpected error: %s", test.name, err)
+		case test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)):
+			t.Errorf("%s: expected error %q, got %v", test.name, test.err, err)
+		}
+	}
+}
+
+func TestNewCheckerFromSpecRejectsUnknownParams(t *testing.T) {
+	spec := config.CheckerSpec{Type: "pods", Name: "shop-pods", Params: map[string]interface{}{"namespace": "shop"}}
+	_, err := NewCheckerFromSpec(KubeConfig{Client: newFakeClient(t, nil, nil)}, spec)
+	if err == nil || !strings.Contains(err.Error(), "shop-pods") || !strings.Contains(err.Error(), `unknown field "namespace"`) {
+		t.Errorf("expected error of misspelled param of shop-pods checker, got %v", err)
+	}
+}
@@ -0,0 +1,173 @@
+package runner
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// defaultProbeInterval is used when no probe interval is configured
+const defaultProbeInterval = 30 * time.Second
+
+// Scheduler runs all checkers periodically in the background and keeps
+// the latest cluster status, so health requests don't hit the API server
+type Scheduler struct {
+	runner   *Runner
+	interval time.Duration
+
+	// firstRun is closed when the first background run completes
+	firstRun chan struct{}
+
+	mu        sync.RWMutex
+	latest    *FinalProbe
+	nextRun   time.Time
+	heartbeat time.Time
+	// ctx is the context of all runs, so a run is not canceled with the request which started it
+	ctx context.Context
+	// call is the run in progress shared by all callers, nil when no run is in progress
+	call *runCall
+}
+
+// runCall is a single run shared by all callers which asked for a run while it was in progress
+type runCall struct {
+	done   chan struct{}
+	result *FinalProbe
+}
+
+// NewScheduler creates Scheduler which runs checks with provided interval
+func NewScheduler(runner *Runner, interval time.Duration) *Scheduler {
+	if interval <= 0 {
+		interval = defaultProbeInterval
+	}
+
+	return &Scheduler{
+		runner:   runner,
+		interval: interval,
+		firstRun: make(chan struct{}),
+		ctx:      context.Background(),
+	}
+}
+
+// Runner returns the runner used by the scheduler
+func (s *Scheduler) Runner() *Runner { return s.runner }
+
+// Interval returns the interval between background runs
+func (s *Scheduler) Interval() time.Duration { return s.interval }
+
+// FirstRun returns a channel which is closed when the first background run completes
+func (s *Scheduler) FirstRun() <-chan struct{} { return s.firstRun }
+
+// Heartbeat returns the time the background loop started or completed the last run.
+// It returns zero time when the loop has not been started.
+func (s *Scheduler) Heartbeat() time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.heartbeat
+}
+
+// Start runs checks in a loop until the context is done.
+// The next run is scheduled interval after the previous one finishes.
+func (s *Scheduler) Start(ctx context.Context) {
+	log.Info().Msgf("starting probe scheduler with interval: %s", s.interval)
+
+	s.mu.Lock()
+	s.ctx = ctx
+	s.mu.Unlock()
+	s.beat()
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for first := true; ; first = false {
+		select {
+		case <-ctx.Done():
+			log.Info().Msg("probe scheduler stopped")
+			return
+		case <-timer.C:
+		}
+
+		<-s.run().done
+
+		s.mu.Lock()
+		s.nextRun = time.Now().Add(s.interval)
+		s.mu.Unlock()
+		s.beat()
+
+		if first {
+			close(s.firstRun)
+		}
+		timer.Reset(s.interval)
+	}
+}
+
+// beat records that the background loop made progress
+func (s *Scheduler) beat() {
+	s.mu.Lock()
+	s.heartbeat = time.Now()
+	s.mu.Unlock()
+}
+
+// RunNow runs all checks immediately and stores the result as the latest one.
+// Callers asking for a run while one is in progress share its result. The run is not bound
+// to the context of the caller, the context only limits how long the caller waits for it.
+func (s *Scheduler) RunNow(ctx context.Context) (*FinalProbe, error) {
+	call := s.run()
+	select {
+	case <-call.done:
+		return call.result, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// run returns the run in progress or starts a new one with the scheduler context
+func (s *Scheduler) run() *runCall {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.call != nil {
+		return s.call
+	}
+	call := &runCall{done: make(chan struct{})}
+	s.call = call
+
+	go func(ctx context.Context) {
+		defer close(call.done)
+		result := s.runner.Run(ctx)
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.call = nil
+		// probes of a canceled run report the cancellation, not the state of the cluster
+		if ctx.Err() == nil && (s.latest == nil || !result.CheckedAt.Before(s.latest.CheckedAt)) {
+			s.latest = result
+		}
+		call.result = s.annotate(result)
+	}(s.ctx)
+
+	return call
+}
+
+// Latest returns the most recent result with its age and the time of the next run.
+// It returns nil when no run has completed yet.
+func (s *Scheduler) Latest() *FinalProbe {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.latest == nil {
+		return nil
+	}
+	return s.annotate(s.latest)
+}
+
+// annotate returns a copy of the result with its age and the time of the next run,
+// it must be called with the lock held
+func (s *Scheduler) annotate(result *FinalProbe) *FinalProbe {
+	annotated := *result
+	annotated.Age = time.Since(annotated.CheckedAt).Round(time.Millisecond).String()
+	annotated.NextRunAt = s.nextRun
+	return &annotated
+}
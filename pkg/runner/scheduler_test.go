@@ -0,0 +1,90 @@
+package runner
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunNowSharesRunInProgress(t *testing.T) {
+	slow := &stubChecker{name: "slow", status: ProbeRunning, delay: 100 * time.Millisecond}
+	runner := newStubRunner(slow)
+	runner.timeout = time.Second
+	scheduler := NewScheduler(runner, time.Minute)
+
+	var wg sync.WaitGroup
+	results := make([]*FinalProbe, 5)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			result, err := scheduler.RunNow(context.Background())
+			if err != nil {
+				t.Error(err)
+			}
+			results[i] = result
+		}(i)
+	}
+	wg.Wait()
+
+	if runs := atomic.LoadInt32(&slow.runs); runs != 1 {
+		t.Errorf("expected concurrent callers to share a single run, got %d runs", runs)
+	}
+	for _, result := range results {
+		if result == nil || !result.CheckedAt.Equal(results[0].CheckedAt) {
+			t.Errorf("expected all callers to get the same result, got %v", result)
+		}
+	}
+}
+
+func TestRunNowIsNotBoundToCaller(t *testing.T) {
+	runner := newStubRunner(&stubChecker{name: "slow", status: ProbeRunning, delay: 50 * time.Millisecond})
+	runner.timeout = time.Second
+	scheduler := NewScheduler(runner, time.Minute)
+
+	// the caller goes away, but the run completes and is stored
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := scheduler.RunNow(ctx); err == nil {
+		t.Fatal("expected error of the caller which stopped waiting")
+	}
+
+	result, err := scheduler.RunNow(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Status != ClusterHealthy {
+		t.Errorf("expected run to not be canceled with the caller, got %s: %v", result.Status, result.Errors)
+	}
+	if latest := scheduler.Latest(); latest == nil || latest.Status != ClusterHealthy {
+		t.Errorf("expected healthy result to be stored, got %v", latest)
+	}
+}
+
+func TestRunNowDoesNotStoreCanceledRun(t *testing.T) {
+	canceling := &cancelingChecker{}
+	scheduler := NewScheduler(newStubRunner(canceling), time.Minute)
+	scheduler.ctx, canceling.cancel = context.WithCancel(context.Background())
+
+	if _, err := scheduler.RunNow(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if latest := scheduler.Latest(); latest != nil {
+		t.Errorf("expected canceled run to not be stored, got %s: %v", latest.Status, latest.Errors)
+	}
+}
+
+// cancelingChecker cancels the scheduler context while it's running, like a shutdown during a run
+type cancelingChecker struct {
+	cancel context.CancelFunc
+}
+
+func (c *cancelingChecker) Name() string { return "canceling" }
+
+func (c *cancelingChecker) Check(ctx context.Context, reporter Reporter) {
+	c.cancel()
+	<-ctx.Done()
+	reporter.Add(NewProbeFromErr(c.Name(), "canceled", ctx.Err()))
+}
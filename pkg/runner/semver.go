@@ -0,0 +1,180 @@
+package runner
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// semver is a parsed semantic version, missing minor and patch numbers are 0
+type semver struct {
+	major, minor, patch int
+	prerelease          string
+}
+
+// parseSemver parses versions like v1.12.3, 1.12 or 1.12.3-rc.1+build
+func parseSemver(version string) (semver, error) {
+	var v semver
+
+	s := strings.TrimPrefix(strings.TrimSpace(version), "v")
+	if i := strings.Index(s, "+"); i >= 0 {
+		s = s[:i]
+	}
+	if i := strings.Index(s, "-"); i >= 0 {
+		s, v.prerelease = s[:i], s[i+1:]
+	}
+
+	parts := strings.Split(s, ".")
+	if len(parts) > 3 || parts[0] == "" {
+		return v, fmt.Errorf("invalid version %q", version)
+	}
+	numbers := []*int{&v.major, &v.minor, &v.patch}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return v, fmt.Errorf("invalid version %q", version)
+		}
+		*numbers[i] = n
+	}
+	return v, nil
+}
+
+// compare returns -1, 0 or 1 when the version is lower, equal or greater than other
+func (v semver) compare(other semver) int {
+	for _, pair := range [][2]int{{v.major, other.major}, {v.minor, other.minor}, {v.patch, other.patch}} {
+		if pair[0] != pair[1] {
+			if pair[0] < pair[1] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return comparePrerelease(v.prerelease, other.prerelease)
+}
+
+// comparePrerelease compares prerelease identifiers, a release is greater than any prerelease
+func comparePrerelease(a, b string) int {
+	switch {
+	case a == b:
+		return 0
+	case a == "":
+		return 1
+	case b == "":
+		return -1
+	}
+
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		if as[i] == bs[i] {
+			continue
+		}
+		an, aErr := strconv.Atoi(as[i])
+		bn, bErr := strconv.Atoi(bs[i])
+		switch {
+		case aErr == nil && bErr == nil && an < bn, aErr == nil && bErr != nil:
+			return -1
+		case aErr == nil && bErr == nil, aErr != nil && bErr == nil:
+			return 1
+		case as[i] < bs[i]:
+			return -1
+		default:
+			return 1
+		}
+	}
+
+	switch {
+	case len(as) < len(bs):
+		return -1
+	case len(as) > len(bs):
+		return 1
+	}
+	return 0
+}
+
+// versionCondition is a single comparison of a version constraint
+type versionCondition struct {
+	op      string
+	version semver
+}
+
+// versionConstraint are alternatives separated by || of conditions separated by commas,
+// i.e. ">=1.10, <1.13 || ^2.0". Supported operators are =, !=, >, >=, <, <=, ~ and ^.
+type versionConstraint [][]versionCondition
+
+// parseVersionConstraint parses the constraint
+func parseVersionConstraint(constraint string) (versionConstraint, error) {
+	var result versionConstraint
+	for _, alternative := range strings.Split(constraint, "||") {
+		var conditions []versionCondition
+		for _, condition := range strings.Split(alternative, ",") {
+			condition = strings.TrimSpace(condition)
+			op := ""
+			for _, candidate := range []string{">=", "<=", "!=", ">", "<", "=", "~", "^"} {
+				if strings.HasPrefix(condition, candidate) {
+					op = candidate
+					break
+				}
+			}
+
+			version, err := parseSemver(strings.TrimSpace(strings.TrimPrefix(condition, op)))
+			if err != nil {
+				return nil, fmt.Errorf("invalid constraint %q: %s", constraint, err)
+			}
+			if op == "" {
+				op = "="
+			}
+			conditions = append(conditions, expandCondition(op, version)...)
+		}
+		result = append(result, conditions)
+	}
+	return result, nil
+}
+
+// expandCondition turns ~ and ^ ranges into comparisons
+func expandCondition(op string, v semver) []versionCondition {
+	switch op {
+	case "~":
+		return []versionCondition{
+			{op: ">=", version: v},
+			{op: "<", version: semver{major: v.major, minor: v.minor + 1, prerelease: "0"}},
+		}
+	case "^":
+		upper := semver{major: v.major + 1, prerelease: "0"}
+		if v.major == 0 {
+			upper = semver{minor: v.minor + 1, prerelease: "0"}
+		}
+		return []versionCondition{{op: ">=", version: v}, {op: "<", version: upper}}
+	}
+	return []versionCondition{{op: op, version: v}}
+}
+
+// check returns true when the version satisfies the constraint
+func (c versionConstraint) check(v semver) bool {
+	for _, conditions := range c {
+		satisfied := true
+		for _, condition := range conditions {
+			cmp := v.compare(condition.version)
+			switch condition.op {
+			case "=":
+				satisfied = cmp == 0
+			case "!=":
+				satisfied = cmp != 0
+			case ">":
+				satisfied = cmp > 0
+			case ">=":
+				satisfied = cmp >= 0
+			case "<":
+				satisfied = cmp < 0
+			case "<=":
+				satisfied = cmp <= 0
+			}
+			if !satisfied {
+				break
+			}
+		}
+		if satisfied {
+			return true
+		}
+	}
+	return false
+}
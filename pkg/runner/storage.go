@@ -0,0 +1,319 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	storagev1beta1 "k8s.io/api/storage/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kube "k8s.io/client-go/kubernetes"
+)
+
+// betaStorageClassAnnotation is the deprecated annotation selecting the storage class of a claim
+const betaStorageClassAnnotation = "volume.beta.kubernetes.io/storage-class"
+
+// selectedNodeAnnotation is set on a claim of a delayed binding storage class once a node of its pod is selected
+const selectedNodeAnnotation = "volume.kubernetes.io/selected-node"
+
+// clusterScope groups problems of volumes which can't be assigned to a namespace
+const clusterScope = "cluster"
+
+// StorageParams configures the storage checker
+type StorageParams struct {
+	// Namespaces are namespaces of checked claims, all namespaces when empty
+	Namespaces []string `json:"namespaces"`
+	// PendingTimeout is the time after which a pending claim or a volume attachment which is still
+	// attaching is reported, 5m by default. Attachments with an attach error are reported immediately.
+	PendingTimeout string `json:"pendingTimeout"`
+}
+
+// StorageProblem is a problem of a single storage object
+type StorageProblem struct {
+	Kind     string        `json:"kind"`
+	Name     string        `json:"name"`
+	Reason   string        `json:"reason"`
+	Message  string        `json:"message,omitempty"`
+	Severity ProbeSeverity `json:"severity"`
+}
+
+// NamespaceStorage is the storage status of a single namespace
+type NamespaceStorage struct {
+	Namespace string           `json:"namespace"`
+	Claims    int              `json:"claims"`
+	Problems  []StorageProblem `json:"problems,omitempty"`
+}
+
+// NewStorageChecker returns a Checker that reports problems of claims, volumes, storage classes and volume attachments
+func NewStorageChecker(config KubeConfig, name string, params StorageParams) (Checker, error) {
+	pendingTimeout := 5 * time.Minute
+	if params.PendingTimeout != "" {
+		var err error
+		if pendingTimeout, err = time.ParseDuration(params.PendingTimeout); err != nil {
+			return nil, fmt.Errorf("invalid pending timeout: %s", err)
+		}
+	}
+
+	namespaces := params.Namespaces
+	if len(namespaces) == 0 {
+		namespaces = []string{metav1.NamespaceAll}
+	}
+
+	return &storageChecker{
+		name:           name,
+		client:         config.Client,
+		namespaces:     namespaces,
+		pendingTimeout: pendingTimeout,
+		now:            time.Now,
+	}, nil
+}
+
+// storageChecker reports storage problems grouped into a probe per namespace
+type storageChecker struct {
+	name           string
+	client         kube.Interface
+	namespaces     []string
+	pendingTimeout time.Duration
+	now            func() time.Time
+}
+
+// Name returns the name of this checker
+func (r *storageChecker) Name() string { return r.name }
+
+// Check validates storage objects and reports a probe for every namespace with claims or problems
+func (r *storageChecker) Check(ctx context.Context, reporter Reporter) {
+	statuses, err := r.storage()
+	if err != nil {
+		reporter.Add(NewProbeFromErr(r.Name(), "failed to query storage", err))
+		return
+	}
+
+	if len(statuses) == 0 {
+		reporter.Add(&Probe{
+			Checker: r.Name(),
+			Status:  ProbeRunning,
+		})
+		return
+	}
+
+	for _, status := range statuses {
+		checker := fmt.Sprintf("%s/%s", r.Name(), status.Namespace)
+		if len(status.Problems) == 0 {
+			reporter.Add(&Probe{
+				Checker:     checker,
+				Status:      ProbeRunning,
+				CheckerData: status,
+			})
+			continue
+		}
+
+		severity := ProbeWarning
+		var problems []string
+		for _, problem := range status.Problems {
+			if problem.Severity == ProbeCritical {
+				severity = ProbeCritical
+			}
+			problems = append(problems, fmt.Sprintf("%s %s is %s", problem.Kind, problem.Name, problem.Reason))
+		}
+		reporter.Add(&Probe{
+			Checker:     checker,
+			Status:      ProbeFailed,
+			Severity:    severity,
+			Error:       fmt.Sprintf("storage problems in %s: %s", status.Namespace, strings.Join(problems, ", ")),
+			CheckerData: status,
+		})
+	}
+}
+
+// storage returns storage statuses sorted by namespace
+func (r *storageChecker) storage() ([]NamespaceStorage, error) {
+	var claims []v1.PersistentVolumeClaim
+	for _, namespace := range r.namespaces {
+		list, err := r.client.CoreV1().PersistentVolumeClaims(namespace).List(metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		claims = append(claims, list.Items...)
+	}
+
+	volumes, err := r.client.CoreV1().PersistentVolumes().List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	classList, err := r.client.StorageV1().StorageClasses().List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	classes := make(map[string]storagev1.StorageClass)
+	for _, class := range classList.Items {
+		classes[class.Name] = class
+	}
+
+	attachments, err := r.client.StorageV1beta1().VolumeAttachments().List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make(map[string]*NamespaceStorage)
+	status := func(namespace string) *NamespaceStorage {
+		if statuses[namespace] == nil {
+			statuses[namespace] = &NamespaceStorage{Namespace: namespace}
+		}
+		return statuses[namespace]
+	}
+
+	for _, claim := range claims {
+		s := status(claim.Namespace)
+		s.Claims++
+		s.Problems = append(s.Problems, r.claimProblems(claim, classes)...)
+	}
+
+	// volumes and attachments are reported in the namespace of their claim
+	volumeNamespaces := make(map[string]string)
+	for _, volume := range volumes.Items {
+		namespace := clusterScope
+		if volume.Spec.ClaimRef != nil {
+			namespace = volume.Spec.ClaimRef.Namespace
+		}
+		volumeNamespaces[volume.Name] = namespace
+
+		if problem, ok := volumeProblem(volume); ok && r.checked(namespace) {
+			status(namespace).Problems = append(status(namespace).Problems, problem)
+		}
+	}
+
+	for _, attachment := range attachments.Items {
+		namespace := clusterScope
+		if source := attachment.Spec.Source.PersistentVolumeName; source != nil && volumeNamespaces[*source] != "" {
+			namespace = volumeNamespaces[*source]
+		}
+
+		if problem, ok := r.attachmentProblem(attachment); ok && r.checked(namespace) {
+			status(namespace).Problems = append(status(namespace).Problems, problem)
+		}
+	}
+
+	var result []NamespaceStorage
+	for _, s := range statuses {
+		result = append(result, *s)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Namespace < result[j].Namespace })
+	return result, nil
+}
+
+// checked returns true when problems of the namespace are reported
+func (r *storageChecker) checked(namespace string) bool {
+	for _, n := range r.namespaces {
+		if n == metav1.NamespaceAll || n == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+// claimProblems returns problems of the claim: stuck phase and a missing storage class.
+// A pending claim of a WaitForFirstConsumer class is not a problem until a node is selected for its pod.
+func (r *storageChecker) claimProblems(claim v1.PersistentVolumeClaim, classes map[string]storagev1.StorageClass) []StorageProblem {
+	var problems []StorageProblem
+	class, classFound := classes[claimStorageClass(claim)]
+
+	switch claim.Status.Phase {
+	case v1.ClaimLost:
+		problems = append(problems, StorageProblem{
+			Kind:     "PersistentVolumeClaim",
+			Name:     claim.Name,
+			Reason:   string(v1.ClaimLost),
+			Message:  fmt.Sprintf("volume %s is lost", claim.Spec.VolumeName),
+			Severity: ProbeCritical,
+		})
+	case v1.ClaimPending:
+		if classFound && waitsForConsumer(claim, class) {
+			break
+		}
+		if pending := r.now().Sub(claim.CreationTimestamp.Time); pending > r.pendingTimeout {
+			problems = append(problems, StorageProblem{
+				Kind:     "PersistentVolumeClaim",
+				Name:     claim.Name,
+				Reason:   string(v1.ClaimPending),
+				Message:  fmt.Sprintf("pending for %s", pending.Round(time.Second)),
+				Severity: ProbeCritical,
+			})
+		}
+	}
+
+	if name := claimStorageClass(claim); name != "" && !classFound {
+		problems = append(problems, StorageProblem{
+			Kind:     "PersistentVolumeClaim",
+			Name:     claim.Name,
+			Reason:   "StorageClassNotFound",
+			Message:  fmt.Sprintf("storage class %s doesn't exist", name),
+			Severity: ProbeCritical,
+		})
+	}
+
+	return problems
+}
+
+// attachmentProblem returns the problem of a volume attachment which failed to attach
+// or is attaching longer than the pending timeout
+func (r *storageChecker) attachmentProblem(attachment storagev1beta1.VolumeAttachment) (StorageProblem, bool) {
+	if attachment.Status.Attached {
+		return StorageProblem{}, false
+	}
+
+	problem := StorageProblem{
+		Kind:     "VolumeAttachment",
+		Name:     attachment.Name,
+		Reason:   "NotAttached",
+		Severity: ProbeCritical,
+	}
+	if attachment.Status.AttachError != nil {
+		problem.Message = attachment.Status.AttachError.Message
+		return problem, true
+	}
+	if attaching := r.now().Sub(attachment.CreationTimestamp.Time); attaching > r.pendingTimeout {
+		problem.Message = fmt.Sprintf("attaching for %s", attaching.Round(time.Second))
+		return problem, true
+	}
+	return StorageProblem{}, false
+}
+
+// claimStorageClass returns the storage class requested by the claim, empty when none is requested
+func claimStorageClass(claim v1.PersistentVolumeClaim) string {
+	if class, ok := claim.Annotations[betaStorageClassAnnotation]; ok {
+		return class
+	}
+	if claim.Spec.StorageClassName != nil {
+		return *claim.Spec.StorageClassName
+	}
+	return ""
+}
+
+// waitsForConsumer returns true when binding of the claim is delayed until a pod using it is scheduled
+func waitsForConsumer(claim v1.PersistentVolumeClaim, class storagev1.StorageClass) bool {
+	if class.VolumeBindingMode == nil || *class.VolumeBindingMode != storagev1.VolumeBindingWaitForFirstConsumer {
+		return false
+	}
+	_, selected := claim.Annotations[selectedNodeAnnotation]
+	return !selected
+}
+
+// volumeProblem returns the problem of a failed or released volume which was not reclaimed
+func volumeProblem(volume v1.PersistentVolume) (StorageProblem, bool) {
+	switch volume.Status.Phase {
+	case v1.VolumeFailed, v1.VolumeReleased:
+		return StorageProblem{
+			Kind:     "PersistentVolume",
+			Name:     volume.Name,
+			Reason:   string(volume.Status.Phase),
+			Message:  volume.Status.Message,
+			Severity: ProbeWarning,
+		}, true
+	}
+	return StorageProblem{}, false
+}
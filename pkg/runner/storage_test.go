@@ -0,0 +1,236 @@
+package runner
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	storagev1beta1 "k8s.io/api/storage/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// testStorageNow is the current time of storage checkers in tests
+var testStorageNow = time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func newTestStorageChecker(t *testing.T, objects []map[string]interface{}, namespaces ...string) *storageChecker {
+	checker, err := NewStorageChecker(KubeConfig{Client: newFakeClient(t, objects, nil)}, "storage", StorageParams{Namespaces: namespaces})
+	if err != nil {
+		t.Fatal(err)
+	}
+	storage := checker.(*storageChecker)
+	storage.now = func() time.Time { return testStorageNow }
+	return storage
+}
+
+func TestClaimProblems(t *testing.T) {
+	checker := newTestStorageChecker(t, nil)
+	className := func(name string) *string { return &name }
+	claim := func(phase v1.PersistentVolumeClaimPhase, age time.Duration, class *string) v1.PersistentVolumeClaim {
+		return v1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: "data", CreationTimestamp: metav1.NewTime(testStorageNow.Add(-age))},
+			Spec:       v1.PersistentVolumeClaimSpec{StorageClassName: class, VolumeName: "pvc-data"},
+			Status:     v1.PersistentVolumeClaimStatus{Phase: phase},
+		}
+	}
+	betaClass := claim(v1.ClaimBound, time.Hour, className("standard"))
+	betaClass.Annotations = map[string]string{betaStorageClassAnnotation: "fast"}
+	nodeSelected := claim(v1.ClaimPending, 10*time.Minute, className("local"))
+	nodeSelected.Annotations = map[string]string{selectedNodeAnnotation: "node-1"}
+
+	waitForConsumer := storagev1.VolumeBindingWaitForFirstConsumer
+	classes := map[string]storagev1.StorageClass{
+		"standard": {ObjectMeta: metav1.ObjectMeta{Name: "standard"}},
+		"local":    {ObjectMeta: metav1.ObjectMeta{Name: "local"}, VolumeBindingMode: &waitForConsumer},
+	}
+
+	tests := []struct {
+		name    string
+		claim   v1.PersistentVolumeClaim
+		reasons []string
+	}{
+		{name: "bound", claim: claim(v1.ClaimBound, time.Hour, className("standard"))},
+		{name: "no storage class", claim: claim(v1.ClaimBound, time.Hour, nil)},
+		{name: "empty storage class", claim: claim(v1.ClaimBound, time.Hour, className(""))},
+		{name: "pending shortly", claim: claim(v1.ClaimPending, time.Minute, className("standard"))},
+		{name: "pending too long", claim: claim(v1.ClaimPending, 10*time.Minute, className("standard")), reasons: []string{"Pending"}},
+		{name: "waiting for first consumer", claim: claim(v1.ClaimPending, 10*time.Minute, className("local"))},
+		{name: "pending on selected node", claim: nodeSelected, reasons: []string{"Pending"}},
+		{name: "lost", claim: claim(v1.ClaimLost, time.Hour, className("standard")), reasons: []string{"Lost"}},
+		{name: "missing storage class", claim: claim(v1.ClaimBound, time.Hour, className("fast")), reasons: []string{"StorageClassNotFound"}},
+		{name: "missing beta storage class", claim: betaClass, reasons: []string{"StorageClassNotFound"}},
+		{
+			name:    "pending with missing storage class",
+			claim:   claim(v1.ClaimPending, time.Hour, className("fast")),
+			reasons: []string{"Pending", "StorageClassNotFound"},
+		},
+	}
+
+	for _, test := range tests {
+		var reasons []string
+		for _, problem := range checker.claimProblems(test.claim, classes) {
+			if problem.Kind != "PersistentVolumeClaim" || problem.Name != "data" || problem.Severity != ProbeCritical {
+				t.Errorf("%s: unexpected problem %+v", test.name, problem)
+			}
+			reasons = append(reasons, problem.Reason)
+		}
+		if !reflect.DeepEqual(reasons, test.reasons) {
+			t.Errorf("%s: expected problems %v, got %v", test.name, test.reasons, reasons)
+		}
+	}
+}
+
+func TestVolumeProblem(t *testing.T) {
+	tests := []struct {
+		phase   v1.PersistentVolumePhase
+		problem bool
+	}{
+		{phase: v1.VolumePending},
+		{phase: v1.VolumeAvailable},
+		{phase: v1.VolumeBound},
+		{phase: v1.VolumeReleased, problem: true},
+		{phase: v1.VolumeFailed, problem: true},
+	}
+
+	for _, test := range tests {
+		volume := v1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: "pvc-data"},
+			Status:     v1.PersistentVolumeStatus{Phase: test.phase, Message: "recycler failed"},
+		}
+		problem, ok := volumeProblem(volume)
+		if ok != test.problem {
+			t.Errorf("%s: expected problem %v, got %v", test.phase, test.problem, ok)
+			continue
+		}
+		expected := StorageProblem{}
+		if test.problem {
+			expected = StorageProblem{
+				Kind:     "PersistentVolume",
+				Name:     "pvc-data",
+				Reason:   string(test.phase),
+				Message:  "recycler failed",
+				Severity: ProbeWarning,
+			}
+		}
+		if problem != expected {
+			t.Errorf("%s: expected %+v, got %+v", test.phase, expected, problem)
+		}
+	}
+}
+
+func TestAttachmentProblem(t *testing.T) {
+	checker := newTestStorageChecker(t, nil)
+	attachment := func(attached bool, age time.Duration, attachError string) storagev1beta1.VolumeAttachment {
+		a := storagev1beta1.VolumeAttachment{
+			ObjectMeta: metav1.ObjectMeta{Name: "csi-data", CreationTimestamp: metav1.NewTime(testStorageNow.Add(-age))},
+			Status:     storagev1beta1.VolumeAttachmentStatus{Attached: attached},
+		}
+		if attachError != "" {
+			a.Status.AttachError = &storagev1beta1.VolumeError{Message: attachError}
+		}
+		return a
+	}
+
+	tests := []struct {
+		name       string
+		attachment storagev1beta1.VolumeAttachment
+		problem    bool
+		message    string
+	}{
+		{name: "attached", attachment: attachment(true, time.Hour, "")},
+		{name: "attaching", attachment: attachment(false, time.Minute, "")},
+		{name: "attaching too long", attachment: attachment(false, 10*time.Minute, ""), problem: true, message: "attaching for 10m0s"},
+		{name: "attach error", attachment: attachment(false, time.Second, "disk is attached to another node"), problem: true, message: "disk is attached to another node"},
+	}
+
+	for _, test := range tests {
+		problem, ok := checker.attachmentProblem(test.attachment)
+		if ok != test.problem {
+			t.Errorf("%s: expected problem %v, got %v", test.name, test.problem, ok)
+			continue
+		}
+		if ok && (problem.Reason != "NotAttached" || problem.Message != test.message || problem.Severity != ProbeCritical) {
+			t.Errorf("%s: unexpected problem %+v", test.name, problem)
+		}
+	}
+}
+
+func TestStorageAssignsAttachmentsToNamespaces(t *testing.T) {
+	volume := func(name, claimNamespace string) map[string]interface{} {
+		spec := map[string]interface{}{}
+		if claimNamespace != "" {
+			spec["claimRef"] = map[string]interface{}{"namespace": claimNamespace, "name": "data"}
+		}
+		return map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "PersistentVolume",
+			"metadata":   map[string]interface{}{"name": name},
+			"spec":       spec,
+			"status":     map[string]interface{}{"phase": "Bound"},
+		}
+	}
+	attachment := func(name, volumeName string) map[string]interface{} {
+		source := map[string]interface{}{}
+		if volumeName != "" {
+			source["persistentVolumeName"] = volumeName
+		}
+		return map[string]interface{}{
+			"apiVersion": "storage.k8s.io/v1beta1",
+			"kind":       "VolumeAttachment",
+			"metadata":   map[string]interface{}{"name": name},
+			"spec":       map[string]interface{}{"attacher": "pd.csi.storage.gke.io", "nodeName": "node-1", "source": source},
+			"status": map[string]interface{}{
+				"attached":    false,
+				"attachError": map[string]interface{}{"message": "disk not found"},
+			},
+		}
+	}
+	objects := []map[string]interface{}{
+		volume("pvc-shop", "shop"),
+		volume("pvc-unclaimed", ""),
+		attachment("csi-shop", "pvc-shop"),
+		attachment("csi-unclaimed", "pvc-unclaimed"),
+		attachment("csi-unknown-volume", "pvc-deleted"),
+		attachment("csi-inline", ""),
+	}
+
+	tests := []struct {
+		namespaces  []string
+		attachments map[string][]string
+	}{
+		{
+			attachments: map[string][]string{
+				"shop":       {"csi-shop"},
+				clusterScope: {"csi-unclaimed", "csi-unknown-volume", "csi-inline"},
+			},
+		},
+		{
+			namespaces:  []string{"shop"},
+			attachments: map[string][]string{"shop": {"csi-shop"}},
+		},
+		{
+			namespaces:  []string{"monitoring"},
+			attachments: map[string][]string{},
+		},
+	}
+
+	for _, test := range tests {
+		statuses, err := newTestStorageChecker(t, objects, test.namespaces...).storage()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		attachments := make(map[string][]string)
+		for _, status := range statuses {
+			for _, problem := range status.Problems {
+				if problem.Kind == "VolumeAttachment" {
+					attachments[status.Namespace] = append(attachments[status.Namespace], problem.Name)
+				}
+			}
+		}
+		if !reflect.DeepEqual(attachments, test.attachments) {
+			t.Errorf("namespaces %v: expected attachments %v, got %v", test.namespaces, test.attachments, attachments)
+		}
+	}
+}
@@ -0,0 +1,276 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	typedappsv1 "k8s.io/client-go/kubernetes/typed/apps/v1"
+)
+
+// progressDeadlineExceeded is the reason of Progressing condition of a stalled deployment rollout
+const progressDeadlineExceeded = "ProgressDeadlineExceeded"
+
+// defaultMinAvailable is the default minimal percentage of available replicas, it matches
+// the default maxUnavailable of deployment rolling updates
+const defaultMinAvailable = 75
+
+// WorkloadsParams selects workloads checked by the workloads checker.
+// Workloads are selected by the label selector when no names are given.
+type WorkloadsParams struct {
+	// Namespace is the namespace of workloads, all namespaces when empty
+	Namespace string `json:"namespace"`
+	// Selector is the label selector of workloads
+	Selector string `json:"selector"`
+	// Deployments are names of deployments
+	Deployments []string `json:"deployments"`
+	// StatefulSets are names of stateful sets
+	StatefulSets []string `json:"statefulSets"`
+	// DaemonSets are names of daemon sets
+	DaemonSets []string `json:"daemonSets"`
+	// MinAvailable is the minimal percentage of desired replicas which must be available, 75 when not set.
+	// Rollouts in progress don't fail the checker while enough replicas are available.
+	MinAvailable *int `json:"minAvailable"`
+}
+
+// hasNames returns true when workloads are selected by names
+func (p WorkloadsParams) hasNames() bool {
+	return len(p.Deployments)+len(p.StatefulSets)+len(p.DaemonSets) > 0
+}
+
+// WorkloadStatus is the rollout status of a single workload
+type WorkloadStatus struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Desired   int32  `json:"desired"`
+	Updated   int32  `json:"updated"`
+	Ready     int32  `json:"ready"`
+	Available int32  `json:"available"`
+	// Stalled is true when the rollout exceeded its progress deadline
+	Stalled bool `json:"stalled"`
+	// Outdated is true when the controller hasn't observed the latest generation of the workload yet,
+	// its rollout status describes the previous generation then
+	Outdated bool `json:"outdated"`
+	// Message is the message of the Progressing condition
+	Message string `json:"message,omitempty"`
+	// Error is the error of fetching the workload
+	Error string `json:"error,omitempty"`
+}
+
+// problems returns descriptions of rollout problems of the workload. Replicas which are not updated
+// yet are not a problem, so rollouts in progress and workloads updated on delete don't fail.
+// The rollout of an outdated workload is not stalled, but it must be available.
+func (w WorkloadStatus) problems(minAvailable int) []string {
+	if w.Error != "" {
+		return []string{w.Error}
+	}
+
+	var problems []string
+	if w.Stalled && !w.Outdated {
+		problems = append(problems, fmt.Sprintf("rollout stalled: %s", w.Message))
+	}
+	if int(w.Available)*100 < int(w.Desired)*minAvailable {
+		problems = append(problems, fmt.Sprintf("%d/%d available (minimum %d%%)", w.Available, w.Desired, minAvailable))
+	}
+	return problems
+}
+
+// NewWorkloadsChecker returns a Checker that reports rollout health of deployments, stateful sets and daemon sets
+func NewWorkloadsChecker(config KubeConfig, name string, params WorkloadsParams) Checker {
+	minAvailable := defaultMinAvailable
+	if params.MinAvailable != nil {
+		minAvailable = *params.MinAvailable
+	}
+	return &workloadsChecker{
+		name:         name,
+		client:       config.Client.AppsV1(),
+		params:       params,
+		minAvailable: minAvailable,
+	}
+}
+
+// workloadsChecker reports every selected workload as a separate probe
+type workloadsChecker struct {
+	name         string
+	client       typedappsv1.AppsV1Interface
+	params       WorkloadsParams
+	minAvailable int
+}
+
+// Name returns the name of this checker
+func (r *workloadsChecker) Name() string { return r.name }
+
+// Check validates the rollout status of selected workloads
+func (r *workloadsChecker) Check(ctx context.Context, reporter Reporter) {
+	statuses, err := r.workloads()
+	if err != nil {
+		reporter.Add(NewProbeFromErr(r.Name(), "failed to query workloads", err))
+		return
+	}
+
+	if len(statuses) == 0 {
+		reporter.Add(NewProbeFromErr(r.Name(), "",
+			fmt.Errorf("no workloads found in namespace %q with selector %q", r.params.Namespace, r.params.Selector)))
+		return
+	}
+
+	for _, status := range statuses {
+		checker := fmt.Sprintf("%s/%s/%s/%s", r.Name(), strings.ToLower(status.Kind), status.Namespace, status.Name)
+		if problems := status.problems(r.minAvailable); len(problems) > 0 {
+			reporter.Add(&Probe{
+				Checker:     checker,
+				Status:      ProbeFailed,
+				Error:       fmt.Sprintf("%s %s is not healthy: %s", status.Kind, status.Name, strings.Join(problems, ", ")),
+				CheckerData: status,
+			})
+			continue
+		}
+
+		reporter.Add(&Probe{
+			Checker:     checker,
+			Status:      ProbeRunning,
+			CheckerData: status,
+		})
+	}
+}
+
+// workloads returns statuses of workloads selected by names or by the label selector
+func (r *workloadsChecker) workloads() ([]WorkloadStatus, error) {
+	if r.params.hasNames() {
+		return r.workloadsByNames()
+	}
+
+	options := metav1.ListOptions{LabelSelector: r.params.Selector}
+	var statuses []WorkloadStatus
+
+	deployments, err := r.client.Deployments(r.params.Namespace).List(options)
+	if err != nil {
+		return nil, err
+	}
+	for _, deployment := range deployments.Items {
+		statuses = append(statuses, deploymentStatus(deployment))
+	}
+
+	statefulSets, err := r.client.StatefulSets(r.params.Namespace).List(options)
+	if err != nil {
+		return nil, err
+	}
+	for _, statefulSet := range statefulSets.Items {
+		statuses = append(statuses, statefulSetStatus(statefulSet))
+	}
+
+	daemonSets, err := r.client.DaemonSets(r.params.Namespace).List(options)
+	if err != nil {
+		return nil, err
+	}
+	for _, daemonSet := range daemonSets.Items {
+		statuses = append(statuses, daemonSetStatus(daemonSet))
+	}
+
+	return statuses, nil
+}
+
+// workloadsByNames returns statuses of workloads given by names, workloads which can't be fetched are reported with the error
+func (r *workloadsChecker) workloadsByNames() ([]WorkloadStatus, error) {
+	var statuses []WorkloadStatus
+
+	for _, name := range r.params.Deployments {
+		deployment, err := r.client.Deployments(r.params.Namespace).Get(name, metav1.GetOptions{})
+		if err != nil {
+			statuses = append(statuses, missingWorkload("Deployment", r.params.Namespace, name, err))
+			continue
+		}
+		statuses = append(statuses, deploymentStatus(*deployment))
+	}
+
+	for _, name := range r.params.StatefulSets {
+		statefulSet, err := r.client.StatefulSets(r.params.Namespace).Get(name, metav1.GetOptions{})
+		if err != nil {
+			statuses = append(statuses, missingWorkload("StatefulSet", r.params.Namespace, name, err))
+			continue
+		}
+		statuses = append(statuses, statefulSetStatus(*statefulSet))
+	}
+
+	for _, name := range r.params.DaemonSets {
+		daemonSet, err := r.client.DaemonSets(r.params.Namespace).Get(name, metav1.GetOptions{})
+		if err != nil {
+			statuses = append(statuses, missingWorkload("DaemonSet", r.params.Namespace, name, err))
+			continue
+		}
+		statuses = append(statuses, daemonSetStatus(*daemonSet))
+	}
+
+	return statuses, nil
+}
+
+func missingWorkload(kind, namespace, name string, err error) WorkloadStatus {
+	return WorkloadStatus{
+		Kind:      kind,
+		Namespace: namespace,
+		Name:      name,
+		Error:     err.Error(),
+	}
+}
+
+func deploymentStatus(deployment appsv1.Deployment) WorkloadStatus {
+	status := WorkloadStatus{
+		Kind:      "Deployment",
+		Namespace: deployment.Namespace,
+		Name:      deployment.Name,
+		Desired:   replicas(deployment.Spec.Replicas),
+		Updated:   deployment.Status.UpdatedReplicas,
+		Ready:     deployment.Status.ReadyReplicas,
+		Available: deployment.Status.AvailableReplicas,
+		Outdated:  deployment.Status.ObservedGeneration < deployment.Generation,
+	}
+
+	for _, condition := range deployment.Status.Conditions {
+		if condition.Type != appsv1.DeploymentProgressing {
+			continue
+		}
+		status.Message = condition.Message
+		status.Stalled = condition.Status == v1.ConditionFalse && condition.Reason == progressDeadlineExceeded
+	}
+
+	return status
+}
+
+func statefulSetStatus(statefulSet appsv1.StatefulSet) WorkloadStatus {
+	// stateful sets don't report available replicas, ready pods are available
+	return WorkloadStatus{
+		Kind:      "StatefulSet",
+		Namespace: statefulSet.Namespace,
+		Name:      statefulSet.Name,
+		Desired:   replicas(statefulSet.Spec.Replicas),
+		Updated:   statefulSet.Status.UpdatedReplicas,
+		Ready:     statefulSet.Status.ReadyReplicas,
+		Available: statefulSet.Status.ReadyReplicas,
+		Outdated:  statefulSet.Status.ObservedGeneration < statefulSet.Generation,
+	}
+}
+
+func daemonSetStatus(daemonSet appsv1.DaemonSet) WorkloadStatus {
+	return WorkloadStatus{
+		Kind:      "DaemonSet",
+		Namespace: daemonSet.Namespace,
+		Name:      daemonSet.Name,
+		Desired:   daemonSet.Status.DesiredNumberScheduled,
+		Updated:   daemonSet.Status.UpdatedNumberScheduled,
+		Ready:     daemonSet.Status.NumberReady,
+		Available: daemonSet.Status.NumberAvailable,
+		Outdated:  daemonSet.Status.ObservedGeneration < daemonSet.Generation,
+	}
+}
+
+// replicas returns the desired number of replicas, kubernetes defaults it to 1
+func replicas(replicas *int32) int32 {
+	if replicas == nil {
+		return 1
+	}
+	return *replicas
+}
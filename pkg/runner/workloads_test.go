@@ -0,0 +1,73 @@
+package runner
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWorkloadProblems(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       WorkloadStatus
+		minAvailable int
+		problems     []string
+	}{
+		{
+			name:         "rollout in progress",
+			status:       WorkloadStatus{Desired: 4, Updated: 1, Ready: 3, Available: 3},
+			minAvailable: 75,
+		},
+		{
+			name:         "not enough available",
+			status:       WorkloadStatus{Desired: 4, Updated: 4, Ready: 2, Available: 2},
+			minAvailable: 75,
+			problems:     []string{"2/4 available (minimum 75%)"},
+		},
+		{
+			name:         "stalled",
+			status:       WorkloadStatus{Desired: 2, Updated: 1, Available: 2, Stalled: true, Message: "timed out"},
+			minAvailable: 75,
+			problems:     []string{"rollout stalled: timed out"},
+		},
+		{
+			name:         "stalled previous generation",
+			status:       WorkloadStatus{Desired: 2, Available: 2, Stalled: true, Outdated: true},
+			minAvailable: 75,
+		},
+		{
+			name:         "outdated and unavailable",
+			status:       WorkloadStatus{Desired: 3, Outdated: true},
+			minAvailable: 75,
+			problems:     []string{"0/3 available (minimum 75%)"},
+		},
+		{
+			name:         "availability not required",
+			status:       WorkloadStatus{Desired: 3},
+			minAvailable: 0,
+		},
+	}
+
+	for _, test := range tests {
+		if problems := test.status.problems(test.minAvailable); !reflect.DeepEqual(problems, test.problems) {
+			t.Errorf("%s: expected problems %v, got %v", test.name, test.problems, problems)
+		}
+	}
+}
+
+func TestWorkloadsCheckerMinAvailable(t *testing.T) {
+	zero := 0
+	tests := []struct {
+		minAvailable *int
+		expected     int
+	}{
+		{minAvailable: nil, expected: defaultMinAvailable},
+		{minAvailable: &zero, expected: 0},
+	}
+
+	for _, test := range tests {
+		checker := NewWorkloadsChecker(KubeConfig{Client: newFakeClient(t, nil, nil)}, "workloads", WorkloadsParams{MinAvailable: test.minAvailable})
+		if minAvailable := checker.(*workloadsChecker).minAvailable; minAvailable != test.expected {
+			t.Errorf("expected minAvailable %d, got %d", test.expected, minAvailable)
+		}
+	}
+}
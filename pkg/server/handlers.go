@@ -1,25 +1,203 @@
 package server
 
 import (
-	"context"
+	"bytes"
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/mateuszdyminski/k8s-status/pkg/runner"
 )
 
+// healthz reports the cluster status with the status code configured for it.
+// In strict mode degraded cluster is reported with the status code of failed one.
+// The format of the status is negotiated with ?format= or the Accept header.
+// The status can be limited to a subset of checkers with ?checker=, ?tag=, ?exclude= and ?minSeverity=.
+// HEAD requests get the status code only. It fails without running checks when the server is draining.
 func (s *Server) healthz(w http.ResponseWriter, r *http.Request) {
-	clusterHealth := s.runner.Run(context.TODO())
+	if state := s.lifecycle.current(); state == stateDraining {
+		writeState(w, r, http.StatusServiceUnavailable, state.String())
+		return
+	}
 
-	data, err := json.Marshal(clusterHealth)
+	format, err := s.negotiate(r)
 	if err != nil {
+		writeState(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	renderer := s.renderers[format]
+
+	clusterHealth, err := s.clusterHealth(r)
+	if err != nil {
+		code := http.StatusBadRequest
+		if r.Context().Err() != nil {
+			code = http.StatusServiceUnavailable
+		}
+		writeState(w, r, code, err.Error())
+		return
+	}
+
+	status := clusterHealth.Status
+	if status == runner.ClusterDegraded && isStrict(r) {
+		status = runner.ClusterFailed
+	}
+	code, ok := s.statusCodes[status]
+	if !ok {
+		code = s.statusCodes[runner.ClusterFailed]
+	}
+
+	w.Header().Set("Vary", "Accept")
+	if r.Method == http.MethodHead {
+		w.Header().Set("Content-Type", renderer.ContentType())
+		w.WriteHeader(code)
+		return
+	}
+
+	var body bytes.Buffer
+	if err := renderer.Render(&body, clusterHealth); err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write([]byte(err.Error()))
+		return
 	}
 
-	w.WriteHeader(http.StatusOK)
-	w.Write(data)
+	w.Header().Set("Content-Type", renderer.ContentType())
+	w.WriteHeader(code)
+	w.Write(body.Bytes())
 }
 
+// clusterHealth returns the latest cluster status or waits for a run when there is none
+// or ?fresh=true is requested. Concurrent requests share the same run. Filtered status
+// is aggregated over the subset of the whole run, a fresh filtered status runs only
+// the selected checkers and their dependencies.
+func (s *Server) clusterHealth(r *http.Request) (*runner.FinalProbe, error) {
+	filter := parseFilter(r)
+	fresh := r.URL.Query().Get("fresh") == "true"
+	if fresh && !filter.IsEmpty() {
+		return s.scheduler.Runner().RunFiltered(r.Context(), filter)
+	}
+
+	clusterHealth := s.scheduler.Latest()
+	if clusterHealth == nil || fresh {
+		var err error
+		if clusterHealth, err = s.scheduler.RunNow(r.Context()); err != nil {
+			return nil, err
+		}
+	}
+
+	if filter.IsEmpty() {
+		return clusterHealth, nil
+	}
+	return s.scheduler.Runner().Subset(clusterHealth, filter)
+}
+
+// parseFilter returns the filter of checkers from the query, list params can be repeated
+// or comma separated, i.e. ?tag=dns,cni or ?tag=dns&tag=cni
+func parseFilter(r *http.Request) runner.Filter {
+	query := r.URL.Query()
+	list := func(key string) []string {
+		var items []string
+		for _, value := range query[key] {
+			for _, item := range strings.Split(value, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					items = append(items, item)
+				}
+			}
+		}
+		return items
+	}
+
+	return runner.Filter{
+		Checkers:    list("checker"),
+		Tags:        list("tag"),
+		Exclude:     list("exclude"),
+		MinSeverity: runner.ProbeSeverity(query.Get("minSeverity")),
+	}
+}
+
+// isStrict returns true when strict mode is requested with ?strict or ?strict=true
+func isStrict(r *http.Request) bool {
+	values, ok := r.URL.Query()["strict"]
+	if !ok {
+		return false
+	}
+	return len(values) == 0 || values[0] == "" || values[0] == "true"
+}
+
+// readyz reports the server is ready only when the first background probe run completed
+// and the server is not draining
 func (s *Server) readyz(w http.ResponseWriter, r *http.Request) {
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("OK"))
+	if state := s.lifecycle.current(); state != stateServing {
+		writeState(w, r, http.StatusServiceUnavailable, state.String())
+		return
+	}
+	writeState(w, r, http.StatusOK, "OK")
+}
+
+// livez fails when the probe scheduler loop made no progress within the liveness timeout,
+// i.e. a run is wedged. It doesn't fail while draining as the loop is stopped then.
+func (s *Server) livez(w http.ResponseWriter, r *http.Request) {
+	heartbeat := s.scheduler.Heartbeat()
+	if s.lifecycle.current() != stateDraining && !heartbeat.IsZero() {
+		if stalled := time.Since(heartbeat); stalled > s.livenessTimeout {
+			writeState(w, r, http.StatusServiceUnavailable,
+				fmt.Sprintf("probe scheduler made no progress for %s", stalled.Round(time.Second)))
+			return
+		}
+	}
+	writeState(w, r, http.StatusOK, "OK")
+}
+
+// writeState writes plain text message with the status code, HEAD requests get the status code only
+func writeState(w http.ResponseWriter, r *http.Request, code int, message string) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(code)
+	if r.Method != http.MethodHead {
+		w.Write([]byte(message))
+	}
+}
+
+// nodesHealthz reports conditions of all nodes in the cluster
+func (s *Server) nodesHealthz(w http.ResponseWriter, r *http.Request) {
+	writeNodeHealth(w, s.scheduler.Runner().NodesHealth(r.Context()))
+}
+
+// nodeHealthz reports conditions of a single node, so it can be used by node level agents
+// and DaemonSet readiness probes
+func (s *Server) nodeHealthz(w http.ResponseWriter, r *http.Request) {
+	writeNodeHealth(w, s.scheduler.Runner().NodeHealth(r.Context(), mux.Vars(r)["name"]))
+}
+
+// writeNodeHealth writes node health with 503 status code when any node is not healthy
+// and 404 status code when the requested node doesn't exist
+func writeNodeHealth(w http.ResponseWriter, nodeHealth *runner.FinalProbe) {
+	data, err := json.Marshal(nodeHealth)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case nodeNotFound(nodeHealth):
+		w.WriteHeader(http.StatusNotFound)
+	case nodeHealth.Status != runner.ClusterHealthy:
+		w.WriteHeader(http.StatusServiceUnavailable)
+	default:
+		w.WriteHeader(http.StatusOK)
+	}
+	w.Write(data)
+}
+
+// nodeNotFound returns true when the node health was requested for a node which doesn't exist
+func nodeNotFound(nodeHealth *runner.FinalProbe) bool {
+	for _, probe := range nodeHealth.Errors {
+		if probe.Code == runner.NodeNotFoundCode {
+			return true
+		}
+	}
+	return false
 }
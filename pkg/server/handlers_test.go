@@ -0,0 +1,244 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mateuszdyminski/k8s-status/pkg/config"
+	"github.com/mateuszdyminski/k8s-status/pkg/runner"
+)
+
+// newTestServer returns Server with a single HTTP checker of the backend failing with the severity
+// and the function closing the backend
+func newTestServer(t *testing.T, cfg *config.Config, backendStatus int, severity string) (*Server, func()) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(backendStatus)
+	}))
+
+	cfg.CheckerConcurrency = 1
+	cfg.CheckerTimeout = 5
+	cfg.Checkers = []config.CheckerSpec{{
+		Type:     "http",
+		Name:     "backend",
+		Severity: severity,
+		Params:   map[string]interface{}{"url": backend.URL},
+	}}
+
+	healthRunner, err := runner.NewRunner(runner.KubeConfig{}, cfg)
+	if err != nil {
+		backend.Close()
+		t.Fatal(err)
+	}
+	return NewServer(cfg, runner.NewScheduler(healthRunner, 0)), backend.Close
+}
+
+func TestHealthzStatusCodes(t *testing.T) {
+	tests := []struct {
+		name          string
+		cfg           config.Config
+		backendStatus int
+		severity      string
+		method        string
+		query         string
+		code          int
+	}{
+		{name: "healthy", backendStatus: http.StatusOK, code: http.StatusOK},
+		{name: "failed", backendStatus: http.StatusInternalServerError, code: http.StatusServiceUnavailable},
+		{name: "degraded", backendStatus: http.StatusInternalServerError, severity: "warning", code: http.StatusOK},
+		{name: "degraded strict", backendStatus: http.StatusInternalServerError, severity: "warning", query: "?strict", code: http.StatusServiceUnavailable},
+		{name: "degraded strict=false", backendStatus: http.StatusInternalServerError, severity: "warning", query: "?strict=false", code: http.StatusOK},
+		{
+			name:          "degraded configured",
+			cfg:           config.Config{DegradedStatusCode: http.StatusTooManyRequests},
+			backendStatus: http.StatusInternalServerError,
+			severity:      "warning",
+			code:          http.StatusTooManyRequests,
+		},
+		{name: "head failed", backendStatus: http.StatusInternalServerError, method: http.MethodHead, code: http.StatusServiceUnavailable},
+		{name: "post", backendStatus: http.StatusOK, method: http.MethodPost, code: http.StatusMethodNotAllowed},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server, closeBackend := newTestServer(t, &test.cfg, test.backendStatus, test.severity)
+			defer closeBackend()
+
+			method := test.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			recorder := httptest.NewRecorder()
+			server.ServeHTTP(recorder, httptest.NewRequest(method, "/healthz"+test.query, nil))
+
+			if recorder.Code != test.code {
+				t.Errorf("expected status code %d, got %d", test.code, recorder.Code)
+			}
+			switch method {
+			case http.MethodHead:
+				if recorder.Body.Len() != 0 {
+					t.Errorf("expected empty body of HEAD request, got %q", recorder.Body.String())
+				}
+			case http.MethodGet:
+				if contentType := recorder.Header().Get("Content-Type"); contentType != "application/json" {
+					t.Errorf("expected application/json content type, got %q", contentType)
+				}
+			}
+		})
+	}
+}
+
+// serve returns the status code and the body of GET request to the path
+func serve(server *Server, path string) (int, string) {
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+	return recorder.Code, recorder.Body.String()
+}
+
+func TestReadyzFollowsLifecycle(t *testing.T) {
+	server, closeBackend := newTestServer(t, &config.Config{}, http.StatusOK, "")
+	defer closeBackend()
+
+	if code, body := serve(server, "/readyz"); code != http.StatusServiceUnavailable || body != "starting" {
+		t.Errorf("expected not ready while starting, got %d %q", code, body)
+	}
+
+	server.lifecycle.transition(stateStarting, stateWarmingUp)
+	if code, body := serve(server, "/readyz"); code != http.StatusServiceUnavailable || body != "warming up" {
+		t.Errorf("expected not ready before the first run, got %d %q", code, body)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go server.scheduler.Start(ctx)
+	<-server.scheduler.FirstRun()
+
+	server.lifecycle.transition(stateWarmingUp, stateServing)
+	if code, body := serve(server, "/readyz"); code != http.StatusOK || body != "OK" {
+		t.Errorf("expected ready after the first run, got %d %q", code, body)
+	}
+
+	server.lifecycle.drain()
+	if server.lifecycle.transition(stateWarmingUp, stateServing) {
+		t.Error("expected draining server to never become serving again")
+	}
+	for _, path := range []string{"/readyz", "/healthz"} {
+		if code, body := serve(server, path); code != http.StatusServiceUnavailable || body != "draining" {
+			t.Errorf("expected %s to fail while draining, got %d %q", path, code, body)
+		}
+	}
+}
+
+func TestLivezDetectsStalledScheduler(t *testing.T) {
+	server, closeBackend := newTestServer(t, &config.Config{}, http.StatusOK, "")
+	defer closeBackend()
+
+	if code, _ := serve(server, "/livez"); code != http.StatusOK {
+		t.Errorf("expected live before the scheduler started, got %d", code)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go server.scheduler.Start(ctx)
+	<-server.scheduler.FirstRun()
+
+	if code, _ := serve(server, "/livez"); code != http.StatusOK {
+		t.Errorf("expected live after the first run, got %d", code)
+	}
+
+	// the scheduler waits for the next run much longer than the liveness timeout
+	server.livenessTimeout = time.Millisecond
+	time.Sleep(10 * time.Millisecond)
+	if code, body := serve(server, "/livez"); code != http.StatusServiceUnavailable || !strings.Contains(body, "no progress") {
+		t.Errorf("expected stalled scheduler to fail liveness, got %d %q", code, body)
+	}
+
+	server.lifecycle.drain()
+	if code, _ := serve(server, "/livez"); code != http.StatusOK {
+		t.Errorf("expected live while draining, got %d", code)
+	}
+}
+
+func TestHealthzFiltersCheckers(t *testing.T) {
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer healthy.Close()
+	var failingCalls int32
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&failingCalls, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+
+	cfg := &config.Config{CheckerConcurrency: 2, CheckerTimeout: 5, Checkers: []config.CheckerSpec{
+		{Type: "http", Name: "dns", Tags: []string{"network"}, Params: map[string]interface{}{"url": healthy.URL}},
+		{Type: "http", Name: "registry", Tags: []string{"storage"}, Params: map[string]interface{}{"url": failing.URL}},
+	}}
+	healthRunner, err := runner.NewRunner(runner.KubeConfig{}, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := NewServer(cfg, runner.NewScheduler(healthRunner, 0))
+
+	tests := []struct {
+		query string
+		code  int
+		body  string
+	}{
+		{query: "", code: http.StatusServiceUnavailable, body: `"status":"failed"`},
+		{query: "?tag=network", code: http.StatusOK, body: `"status":"healthy"`},
+		{query: "?exclude=registry&fresh=true", code: http.StatusOK, body: `"status":"healthy"`},
+		{query: "?checker=dns,registry", code: http.StatusServiceUnavailable, body: `"status":"failed"`},
+		{query: "?checker=ntp", code: http.StatusBadRequest, body: "unknown checker: ntp"},
+		{query: "?minSeverity=fatal", code: http.StatusBadRequest, body: "unknown severity: fatal"},
+	}
+
+	for _, test := range tests {
+		code, body := serve(server, "/healthz"+test.query)
+		if code != test.code || !strings.Contains(body, test.body) {
+			t.Errorf("%q: expected %d with %s, got %d %s", test.query, test.code, test.body, code, body)
+		}
+	}
+
+	// the fresh filtered status runs only the selected checkers
+	calls := atomic.LoadInt32(&failingCalls)
+	if code, body := serve(server, "/healthz?tag=network&fresh=true"); code != http.StatusOK {
+		t.Errorf("expected fresh status of network checkers to be healthy, got %d %s", code, body)
+	}
+	if after := atomic.LoadInt32(&failingCalls); after != calls {
+		t.Errorf("expected registry checker to not run, it was called %d times", after-calls)
+	}
+}
+
+func TestWriteNodeHealthStatusCodes(t *testing.T) {
+	tests := []struct {
+		name   string
+		health runner.FinalProbe
+		code   int
+	}{
+		{name: "ready", health: runner.FinalProbe{Status: runner.ClusterHealthy}, code: http.StatusOK},
+		{
+			name:   "not ready",
+			health: runner.FinalProbe{Status: runner.ClusterDegraded, Errors: []runner.SingleFinalProbe{{Checker: "nodestatus/node-1"}}},
+			code:   http.StatusServiceUnavailable,
+		},
+		{
+			name: "unknown node",
+			health: runner.FinalProbe{Status: runner.ClusterFailed, Errors: []runner.SingleFinalProbe{
+				{Checker: "nodestatus/node-3", Code: runner.NodeNotFoundCode},
+			}},
+			code: http.StatusNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		recorder := httptest.NewRecorder()
+		writeNodeHealth(recorder, &test.health)
+		if recorder.Code != test.code {
+			t.Errorf("%s: expected status code %d, got %d", test.name, test.code, recorder.Code)
+		}
+	}
+}
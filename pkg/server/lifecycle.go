@@ -0,0 +1,64 @@
+package server
+
+import (
+	"sync/atomic"
+
+	"github.com/rs/zerolog/log"
+)
+
+// lifecycleState is the state of the server lifecycle
+type lifecycleState int32
+
+const (
+	// stateStarting is the state until the HTTP server listens
+	stateStarting lifecycleState = iota
+	// stateWarmingUp is the state until the first background probe run completes
+	stateWarmingUp
+	// stateServing is the state of the server ready to serve traffic
+	stateServing
+	// stateDraining is the state after the shutdown started, it's never left
+	stateDraining
+)
+
+func (s lifecycleState) String() string {
+	switch s {
+	case stateStarting:
+		return "starting"
+	case stateWarmingUp:
+		return "warming up"
+	case stateServing:
+		return "serving"
+	case stateDraining:
+		return "draining"
+	}
+	return "unknown"
+}
+
+// lifecycle holds the server lifecycle state, it's safe for concurrent use
+type lifecycle struct {
+	state int32
+}
+
+// current returns the current state
+func (l *lifecycle) current() lifecycleState {
+	return lifecycleState(atomic.LoadInt32(&l.state))
+}
+
+// transition moves to the state only when the current state is the expected one,
+// so e.g. the server which already started draining never becomes serving again
+func (l *lifecycle) transition(from, to lifecycleState) bool {
+	if !atomic.CompareAndSwapInt32(&l.state, int32(from), int32(to)) {
+		return false
+	}
+
+	log.Info().Msgf("server lifecycle: %s -> %s", from, to)
+	return true
+}
+
+// drain moves to the draining state from any state
+func (l *lifecycle) drain() {
+	from := lifecycleState(atomic.SwapInt32(&l.state, int32(stateDraining)))
+	if from != stateDraining {
+		log.Info().Msgf("server lifecycle: %s -> %s", from, stateDraining)
+	}
+}
@@ -0,0 +1,110 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mateuszdyminski/k8s-status/pkg/runner"
+)
+
+// defaultFormat is rendered when no format is requested or none of the requested ones is supported
+const defaultFormat = "json"
+
+// Renderer writes the cluster status in a single output format
+type Renderer interface {
+	// MediaTypes are the media types of the Accept header served by the renderer
+	MediaTypes() []string
+	// ContentType is the Content-Type header of the rendered status
+	ContentType() string
+	// Render writes the cluster status
+	Render(w io.Writer, status *runner.FinalProbe) error
+}
+
+// WithRenderer registers the renderer of the format served by /healthz, it replaces
+// the renderer already registered for the format
+func WithRenderer(format string, renderer Renderer) func(*Server) {
+	return func(s *Server) {
+		s.addRenderer(format, renderer)
+	}
+}
+
+// addRenderer registers the renderer, renderers are matched with the Accept header
+// in the order of registration
+func (s *Server) addRenderer(format string, renderer Renderer) {
+	if s.renderers == nil {
+		s.renderers = make(map[string]Renderer)
+	}
+	if _, ok := s.renderers[format]; !ok {
+		s.formats = append(s.formats, format)
+	}
+	s.renderers[format] = renderer
+}
+
+// negotiate returns the format requested with ?format= or the Accept header.
+// The default format is returned when no acceptable format is requested in the Accept header,
+// while unknown ?format= is an error.
+func (s *Server) negotiate(r *http.Request) (string, error) {
+	if format := r.URL.Query().Get("format"); format != "" {
+		if _, ok := s.renderers[format]; !ok {
+			return "", fmt.Errorf("unsupported format %q, supported formats: %s", format, strings.Join(s.formats, ", "))
+		}
+		return format, nil
+	}
+
+	for _, mediaRange := range parseAccept(r.Header.Get("Accept")) {
+		for _, format := range s.formats {
+			for _, mediaType := range s.renderers[format].MediaTypes() {
+				if matchMediaType(mediaRange, mediaType) {
+					return format, nil
+				}
+			}
+		}
+	}
+	return defaultFormat, nil
+}
+
+// parseAccept returns media ranges of the Accept header ordered by their quality,
+// media ranges with zero quality are dropped
+func parseAccept(header string) []string {
+	type mediaRange struct {
+		mediaType string
+		quality   float64
+	}
+
+	var ranges []mediaRange
+	for _, part := range strings.Split(header, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			if quality, err = strconv.ParseFloat(q, 64); err != nil {
+				continue
+			}
+		}
+		if quality > 0 {
+			ranges = append(ranges, mediaRange{mediaType: mediaType, quality: quality})
+		}
+	}
+	sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].quality > ranges[j].quality })
+
+	mediaTypes := make([]string, 0, len(ranges))
+	for _, r := range ranges {
+		mediaTypes = append(mediaTypes, r.mediaType)
+	}
+	return mediaTypes
+}
+
+// matchMediaType returns true when the media range, i.e. text/* or */*, contains the media type
+func matchMediaType(mediaRange, mediaType string) bool {
+	if mediaRange == "*/*" || mediaRange == mediaType {
+		return true
+	}
+	return strings.HasSuffix(mediaRange, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(mediaRange, "*"))
+}
@@ -0,0 +1,169 @@
+package server
+
+import (
+	"bytes"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mateuszdyminski/k8s-status/pkg/config"
+	"github.com/mateuszdyminski/k8s-status/pkg/runner"
+)
+
+func TestNegotiate(t *testing.T) {
+	server, closeBackend := newTestServer(t, &config.Config{}, http.StatusOK, "")
+	defer closeBackend()
+
+	tests := []struct {
+		query  string
+		accept string
+		format string
+		err    bool
+	}{
+		{format: "json"},
+		{accept: "*/*", format: "json"},
+		{accept: "text/plain", format: "text"},
+		{accept: "text/*", format: "text"},
+		{accept: "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", format: "html"},
+		{accept: "text/html;q=0.5, text/markdown", format: "markdown"},
+		{accept: "application/junit+xml", format: "junit"},
+		{accept: "image/png", format: "json"},
+		{accept: "text/plain;q=0, text/html", format: "html"},
+		{query: "?format=markdown", accept: "text/html", format: "markdown"},
+		{query: "?format=yaml", err: true},
+	}
+
+	for _, test := range tests {
+		request := httptest.NewRequest(http.MethodGet, "/healthz"+test.query, nil)
+		if test.accept != "" {
+			request.Header.Set("Accept", test.accept)
+		}
+
+		format, err := server.negotiate(request)
+		if test.err {
+			if err == nil {
+				t.Errorf("%s %q: expected error, got format %s", test.query, test.accept, format)
+			}
+			continue
+		}
+		if err != nil || format != test.format {
+			t.Errorf("%s %q: expected format %s, got %s (error: %v)", test.query, test.accept, test.format, format, err)
+		}
+	}
+}
+
+func TestRenderers(t *testing.T) {
+	status := &runner.FinalProbe{
+		Status:  runner.ClusterFailed,
+		Drivers: []string{"api"},
+		Errors: []runner.SingleFinalProbe{{
+			Checker:     "api",
+			Status:      runner.ProbeFailed,
+			Description: "Check api: unexpected status 500 | <retry>",
+			Code:        "500",
+			Severity:    runner.ProbeCritical,
+		}},
+		Skipped:   []runner.SingleFinalProbe{{Checker: "pods", Status: runner.ProbeSkipped, Description: "Check pods: dependency api failed"}},
+		Oks:       []runner.SingleFinalProbe{{Checker: "dns", Status: runner.ProbeRunning, Description: "Check dns: OK"}},
+		CheckedAt: time.Date(2019, 3, 1, 10, 0, 0, 0, time.UTC),
+	}
+
+	tests := []struct {
+		renderer Renderer
+		contains []string
+	}{
+		{
+			renderer: textRenderer{},
+			contains: []string{
+				"Status:     failed\n",
+				"Checked at: 2019-03-01T10:00:00Z\n",
+				"api      failed   critical  500   Check api: unexpected status 500 | <retry>\n",
+				"dns      running  -         -     Check dns: OK\n",
+			},
+		},
+		{
+			renderer: htmlRenderer{},
+			contains: []string{
+				`<span class="status failed">failed</span></h1>`,
+				"<p>Drivers: api</p>",
+				"<td>Check api: unexpected status 500 | &lt;retry&gt;</td>",
+			},
+		},
+		{
+			renderer: markdownRenderer{},
+			contains: []string{
+				"## Cluster status: **failed**\n",
+				"Drivers: `api`",
+				"| api | failed | critical | 500 | Check api: unexpected status 500 \\| <retry> |\n",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		var out bytes.Buffer
+		if err := test.renderer.Render(&out, status); err != nil {
+			t.Fatalf("%T: %s", test.renderer, err)
+		}
+		for _, expected := range test.contains {
+			if !strings.Contains(out.String(), expected) {
+				t.Errorf("%T: expected output to contain %q, got:\n%s", test.renderer, expected, out.String())
+			}
+		}
+	}
+}
+
+func TestJUnitRenderer(t *testing.T) {
+	status := &runner.FinalProbe{
+		Status:  runner.ClusterDegraded,
+		Errors:  []runner.SingleFinalProbe{{Checker: "events", Status: runner.ProbeFailed, Description: "Check events: 12 FailedScheduling", Severity: runner.ProbeWarning}},
+		Skipped: []runner.SingleFinalProbe{{Checker: "pods", Status: runner.ProbeSkipped, Description: "Check pods: dependency failed"}},
+		Oks:     []runner.SingleFinalProbe{{Checker: "dns", Status: runner.ProbeRunning}},
+	}
+
+	var out bytes.Buffer
+	if err := (junitRenderer{}).Render(&out, status); err != nil {
+		t.Fatal(err)
+	}
+
+	var report junitTestSuites
+	if err := xml.Unmarshal(out.Bytes(), &report); err != nil {
+		t.Fatalf("invalid XML: %s\n%s", err, out.String())
+	}
+	if len(report.Suites) != 1 {
+		t.Fatalf("expected single test suite, got %d", len(report.Suites))
+	}
+	suite := report.Suites[0]
+	if suite.Tests != 3 || suite.Failures != 1 || suite.Skipped != 1 {
+		t.Errorf("expected 3 tests, 1 failure and 1 skipped, got %d, %d and %d", suite.Tests, suite.Failures, suite.Skipped)
+	}
+	if failure := suite.Cases[0].Failure; failure == nil || failure.Type != "warning" || failure.Message != "Check events: 12 FailedScheduling" {
+		t.Errorf("unexpected failure of events: %+v", failure)
+	}
+}
+
+func TestHealthzRendersNegotiatedFormat(t *testing.T) {
+	server, closeBackend := newTestServer(t, &config.Config{}, http.StatusInternalServerError, "")
+	defer closeBackend()
+
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+	request.Header.Set("Accept", "text/plain")
+	server.ServeHTTP(recorder, request)
+
+	if recorder.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status code of failed cluster, got %d", recorder.Code)
+	}
+	if contentType := recorder.Header().Get("Content-Type"); contentType != "text/plain; charset=utf-8" {
+		t.Errorf("expected text content type, got %q", contentType)
+	}
+	if !strings.HasPrefix(recorder.Body.String(), "Status:     failed\n") {
+		t.Errorf("expected text table, got:\n%s", recorder.Body.String())
+	}
+
+	if code, body := serve(server, "/healthz?format=yaml"); code != http.StatusBadRequest || !strings.Contains(body, "supported formats: json, text, html, markdown, junit") {
+		t.Errorf("expected bad request for unsupported format, got %d %q", code, body)
+	}
+}
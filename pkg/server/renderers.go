@@ -0,0 +1,247 @@
+package server
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mateuszdyminski/k8s-status/pkg/runner"
+)
+
+// defaultRenderers registers renderers of all built-in formats, JSON goes first
+// so it's served for */* Accept header
+func defaultRenderers(s *Server) {
+	s.addRenderer("json", jsonRenderer{})
+	s.addRenderer("text", textRenderer{})
+	s.addRenderer("html", htmlRenderer{})
+	s.addRenderer("markdown", markdownRenderer{})
+	s.addRenderer("junit", junitRenderer{})
+}
+
+// reportedProbes returns all probes of the status, the config probe goes first when it's set
+// and then failed, skipped and passed probes
+func reportedProbes(status *runner.FinalProbe) []runner.SingleFinalProbe {
+	var probes []runner.SingleFinalProbe
+	if status.Config.Checker != "" {
+		probes = append(probes, status.Config)
+	}
+	probes = append(probes, status.Errors...)
+	probes = append(probes, status.Skipped...)
+	return append(probes, status.Oks...)
+}
+
+// timestamp formats the time of the status, zero time is formatted as empty string
+func timestamp(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// singleLine replaces line breaks, so the text fits into a table cell
+func singleLine(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// jsonRenderer renders FinalProbe as JSON, it's the default format
+type jsonRenderer struct{}
+
+func (jsonRenderer) MediaTypes() []string { return []string{"application/json"} }
+
+func (jsonRenderer) ContentType() string { return "application/json" }
+
+func (jsonRenderer) Render(w io.Writer, status *runner.FinalProbe) error {
+	data, err := json.Marshal(status)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// textRenderer renders a plain text table readable in a terminal
+type textRenderer struct{}
+
+func (textRenderer) MediaTypes() []string { return []string{"text/plain"} }
+
+func (textRenderer) ContentType() string { return "text/plain; charset=utf-8" }
+
+func (textRenderer) Render(w io.Writer, status *runner.FinalProbe) error {
+	fmt.Fprintf(w, "Status:     %s\n", status.Status)
+	if len(status.Drivers) > 0 {
+		fmt.Fprintf(w, "Drivers:    %s\n", strings.Join(status.Drivers, ", "))
+	}
+	fmt.Fprintf(w, "Checked at: %s", timestamp(status.CheckedAt))
+	if status.Age != "" {
+		fmt.Fprintf(w, " (%s ago)", status.Age)
+	}
+	fmt.Fprintln(w)
+	if !status.NextRunAt.IsZero() {
+		fmt.Fprintf(w, "Next run:   %s\n", timestamp(status.NextRunAt))
+	}
+	fmt.Fprintln(w)
+
+	table := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(table, "CHECKER\tSTATUS\tSEVERITY\tCODE\tDESCRIPTION")
+	for _, probe := range reportedProbes(status) {
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\n", probe.Checker, probe.Status,
+			orDash(string(probe.Severity)), orDash(probe.Code), singleLine(probe.Description))
+	}
+	return table.Flush()
+}
+
+// orDash returns dash for empty values, so columns of the text table don't shift
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+// htmlTemplate is the self-contained status page, styles are inlined so it has no external resources
+var htmlTemplate = template.Must(template.New("status").Funcs(template.FuncMap{
+	"probes":    reportedProbes,
+	"timestamp": timestamp,
+	"join":      strings.Join,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Cluster status: {{.Status}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin-top: 1em; }
+th, td { border: 1px solid #ddd; padding: 0.4em 0.8em; text-align: left; vertical-align: top; }
+th { background: #f5f5f5; }
+.status { display: inline-block; padding: 0.1em 0.5em; border-radius: 4px; color: #fff; background: #757575; }
+.healthy, .running { background: #2e7d32; }
+.degraded, .warning { background: #ef6c00; }
+.failed, .critical { background: #c62828; }
+</style>
+</head>
+<body>
+<h1>Cluster status: <span class="status {{.Status}}">{{.Status}}</span></h1>
+{{with .Drivers}}<p>Drivers: {{join . ", "}}</p>
+{{end}}<p>Checked at {{timestamp .CheckedAt}}{{with .Age}} ({{.}} ago){{end}}{{if not .NextRunAt.IsZero}}, next run at {{timestamp .NextRunAt}}{{end}}</p>
+<table>
+<tr><th>Checker</th><th>Status</th><th>Severity</th><th>Code</th><th>Description</th></tr>
+{{range probes .}}<tr><td>{{.Checker}}</td><td><span class="status {{.Status}}">{{.Status}}</span></td><td>{{with .Severity}}<span class="status {{.}}">{{.}}</span>{{end}}</td><td>{{.Code}}</td><td>{{.Description}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+// htmlRenderer renders a status page for browsers
+type htmlRenderer struct{}
+
+func (htmlRenderer) MediaTypes() []string { return []string{"text/html", "application/xhtml+xml"} }
+
+func (htmlRenderer) ContentType() string { return "text/html; charset=utf-8" }
+
+func (htmlRenderer) Render(w io.Writer, status *runner.FinalProbe) error {
+	return htmlTemplate.Execute(w, status)
+}
+
+// markdownRenderer renders Markdown which can be pasted into incident channels
+type markdownRenderer struct{}
+
+func (markdownRenderer) MediaTypes() []string { return []string{"text/markdown", "text/x-markdown"} }
+
+func (markdownRenderer) ContentType() string { return "text/markdown; charset=utf-8" }
+
+func (markdownRenderer) Render(w io.Writer, status *runner.FinalProbe) error {
+	fmt.Fprintf(w, "## Cluster status: **%s**\n\n", status.Status)
+	if len(status.Drivers) > 0 {
+		fmt.Fprintf(w, "Drivers: `%s`  \n", strings.Join(status.Drivers, "`, `"))
+	}
+	fmt.Fprintf(w, "Checked at: %s", timestamp(status.CheckedAt))
+	if status.Age != "" {
+		fmt.Fprintf(w, " (%s ago)", status.Age)
+	}
+	fmt.Fprint(w, "\n\n| Checker | Status | Severity | Code | Description |\n|---|---|---|---|---|\n")
+
+	for _, probe := range reportedProbes(status) {
+		_, err := fmt.Fprintf(w, "| %s | %s | %s | %s | %s |\n", markdownCell(probe.Checker), probe.Status,
+			probe.Severity, markdownCell(probe.Code), markdownCell(probe.Description))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// markdownCell escapes the text, so it doesn't break the Markdown table
+func markdownCell(text string) string {
+	return strings.Replace(singleLine(text), "|", `\|`, -1)
+}
+
+// junitTestSuites is the root element of the JUnit XML report
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Timestamp string          `xml:"timestamp,attr,omitempty"`
+	Cases     []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitMessage `xml:"failure"`
+	Skipped   *junitMessage `xml:"skipped"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr,omitempty"`
+	Text    string `xml:",chardata"`
+}
+
+// junitRenderer renders the JUnit XML report for CI pipelines, every probe is a test case
+type junitRenderer struct{}
+
+func (junitRenderer) MediaTypes() []string {
+	return []string{"application/junit+xml", "application/xml", "text/xml"}
+}
+
+func (junitRenderer) ContentType() string { return "application/xml; charset=utf-8" }
+
+func (junitRenderer) Render(w io.Writer, status *runner.FinalProbe) error {
+	suite := junitTestSuite{Name: "k8s-status", Timestamp: timestamp(status.CheckedAt)}
+	for _, probe := range reportedProbes(status) {
+		testCase := junitTestCase{Name: probe.Checker, ClassName: "k8s-status"}
+		switch probe.Status {
+		case runner.ProbeRunning:
+		case runner.ProbeSkipped:
+			suite.Skipped++
+			testCase.Skipped = &junitMessage{Message: probe.Description}
+		default:
+			suite.Failures++
+			testCase.Failure = &junitMessage{Message: probe.Description, Type: string(probe.Severity), Text: probe.Code}
+		}
+		suite.Tests++
+		suite.Cases = append(suite.Cases, testCase)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(junitTestSuites{Suites: []junitTestSuite{suite}}); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
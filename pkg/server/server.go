@@ -3,9 +3,9 @@ package server
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/http"
 	"runtime"
-	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/mux"
@@ -15,19 +15,35 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
-var (
-	healthy int32 = 1
-	ready   int32 = 1
-)
-
 type Server struct {
-	mux    *mux.Router
-	runner *runner.Runner
+	mux       *mux.Router
+	scheduler *runner.Scheduler
+	// statusCodes are status codes of /healthz by the cluster status
+	statusCodes map[runner.ClusterStatusType]int
+	// livenessTimeout is the time without progress of the scheduler after which /livez fails
+	livenessTimeout time.Duration
+	lifecycle       lifecycle
+	// renderers are renderers of /healthz by the format, formats are in the order of registration
+	renderers map[string]Renderer
+	formats   []string
 }
 
-func NewServer(cfg *config.Config, runner *runner.Runner, options ...func(*Server)) *Server {
-	s := &Server{runner: runner, mux: mux.NewRouter()}
+func NewServer(cfg *config.Config, scheduler *runner.Scheduler, options ...func(*Server)) *Server {
+	s := &Server{
+		scheduler: scheduler,
+		mux:       mux.NewRouter(),
+		statusCodes: map[runner.ClusterStatusType]int{
+			runner.ClusterHealthy:  statusCodeOrDefault(cfg.HealthyStatusCode, http.StatusOK),
+			runner.ClusterDegraded: statusCodeOrDefault(cfg.DegradedStatusCode, http.StatusOK),
+			runner.ClusterFailed:   statusCodeOrDefault(cfg.FailedStatusCode, http.StatusServiceUnavailable),
+		},
+		livenessTimeout: time.Duration(cfg.LivenessTimeout) * time.Second,
+	}
+	if s.livenessTimeout <= 0 {
+		s.livenessTimeout = 3 * scheduler.Interval()
+	}
 
+	defaultRenderers(s)
 	for _, f := range options {
 		f(s)
 	}
@@ -36,44 +52,70 @@ func NewServer(cfg *config.Config, runner *runner.Runner, options ...func(*Serve
 	s.mux.Handle("/metrics", promhttp.Handler())
 
 	// register general handlers
-	s.mux.HandleFunc("/healthz", s.healthz)
+	s.mux.HandleFunc("/healthz", s.healthz).Methods(http.MethodGet, http.MethodHead)
+	s.mux.HandleFunc("/healthz/nodes", s.nodesHealthz)
+	s.mux.HandleFunc("/healthz/nodes/{name}", s.nodeHealthz)
 	s.mux.HandleFunc("/readyz", s.readyz)
+	s.mux.HandleFunc("/livez", s.livez)
 
 	return s
 }
 
+// statusCodeOrDefault returns the default status code when the configured one is not set
+func statusCodeOrDefault(code, defaultCode int) int {
+	if code == 0 {
+		return defaultCode
+	}
+	return code
+}
+
 func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Server", runtime.Version())
 
 	s.mux.ServeHTTP(w, r)
 }
 
-func ListenAndServe(cancelCtx context.Context, runner *runner.Runner, cfg *config.Config) {
+func ListenAndServe(cancelCtx context.Context, scheduler *runner.Scheduler, cfg *config.Config) {
 	inst := NewInstrument()
+	server := NewServer(cfg, scheduler)
 	srv := &http.Server{
 		Addr:         fmt.Sprintf(":%d", cfg.HTTPPort),
-		Handler:      inst.Wrap(NewServer(cfg, runner)),
+		Handler:      inst.Wrap(server),
 		ReadTimeout:  5 * time.Second,
 		WriteTimeout: 1 * time.Minute,
 		IdleTimeout:  15 * time.Second,
 	}
 
+	listener, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
+		log.Fatal().Err(err).Msg("HTTP server can't listen")
+	}
+
 	// run server in background
 	go func() {
 		log.Info().Msgf("HTTP Server started at port: %d", cfg.HTTPPort)
-		if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+		if err := srv.Serve(listener); err != http.ErrServerClosed {
 			log.Fatal().Err(err).Msg("HTTP server crashed")
 		}
 	}()
 
+	// the server is ready once the first background probe run completes
+	server.lifecycle.transition(stateStarting, stateWarmingUp)
+	go func() {
+		select {
+		case <-scheduler.FirstRun():
+			server.lifecycle.transition(stateWarmingUp, stateServing)
+		case <-cancelCtx.Done():
+		}
+	}()
+
 	// wait for SIGTERM or SIGINT
 	<-cancelCtx.Done()
 	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.GracefulShutdownTimeout)*time.Second)
 	defer cancel()
 
 	// all calls to /healthz and /readyz will fail from now on
-	atomic.StoreInt32(&healthy, 0)
-	atomic.StoreInt32(&ready, 0)
+	server.lifecycle.drain()
 
 	time.Sleep(time.Duration(int64(cfg.GracefulShutdownExtraSleep) * int64(time.Second)))
 